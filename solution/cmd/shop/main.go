@@ -1,20 +1,32 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"time"
 
-	"github.com/go-chi/chi/middleware"
-	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/wdsjk/avito-shop/internal/allowance"
+	"github.com/wdsjk/avito-shop/internal/cart"
 	"github.com/wdsjk/avito-shop/internal/config"
 	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/idempotency"
+	"github.com/wdsjk/avito-shop/internal/identity"
+	"github.com/wdsjk/avito-shop/internal/infra/oidc"
 	"github.com/wdsjk/avito-shop/internal/infra/storage"
+	"github.com/wdsjk/avito-shop/internal/infra/storage/migrations"
 	"github.com/wdsjk/avito-shop/internal/infra/storage/postgres"
 	"github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers"
-	mwauth "github.com/wdsjk/avito-shop/internal/infra/transport/http/middleware/auth"
-	mwlogger "github.com/wdsjk/avito-shop/internal/infra/transport/http/middleware/logger"
 	"github.com/wdsjk/avito-shop/internal/infra/transport/http/server"
+	"github.com/wdsjk/avito-shop/internal/ledger"
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
+	"github.com/wdsjk/avito-shop/internal/lot"
+	"github.com/wdsjk/avito-shop/internal/order"
+	"github.com/wdsjk/avito-shop/internal/scheduler"
+	"github.com/wdsjk/avito-shop/internal/session"
 	"github.com/wdsjk/avito-shop/internal/shop"
 	"github.com/wdsjk/avito-shop/internal/transfer"
 )
@@ -38,32 +50,152 @@ func main() {
 		os.Exit(1)
 	}
 
-	shop := shop.NewShop()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), storage, log, os.Args[2:]); err != nil {
+			log.Error("failed to migrate", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.MigrateOnStart {
+		migrator, err := migrations.NewMigrator(storage)
+		if err != nil {
+			log.Error("failed to load migrations", "error", err)
+			os.Exit(1)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Error("failed to apply migrations", "error", err)
+			os.Exit(1)
+		}
+		log.Info("migrations are up to date", "applied", applied)
+	}
+
+	keys, err := setupKeys(cfg)
+	if err != nil {
+		log.Error("failed to load signing keys", "error", err)
+		os.Exit(1)
+	}
+
+	shopRepo := postgres.NewShopRepository(storage)
+	shopService := shop.NewShopService(shopRepo)
+
+	welcome := employee.WelcomePolicy{
+		Amount:         cfg.WelcomeGrant.Amount,
+		Channels:       cfg.WelcomeGrant.Channels,
+		Departments:    cfg.WelcomeGrant.Departments,
+		VestingPercent: cfg.WelcomeGrant.VestingPercent,
+		VestAfter:      cfg.WelcomeGrant.VestAfter,
+	}
+	if err := welcome.Validate(); err != nil {
+		log.Error("failed to load welcome grant policy", "error", err)
+		os.Exit(1)
+	}
+
+	transferLoc, err := time.LoadLocation(cfg.TransferRules.Timezone)
+	if err != nil {
+		log.Error("failed to load transfer rules", "error", err)
+		os.Exit(1)
+	}
+	rules := transfer.Rules{
+		MaxPerTransfer:          cfg.TransferRules.MaxPerTransfer,
+		MaxPerDay:               cfg.TransferRules.MaxPerDay,
+		MaxPerCounterpartPerDay: cfg.TransferRules.MaxPerCounterpartPerDay,
+		Cooldown:                cfg.TransferRules.Cooldown,
+		ReturnCooldown:          cfg.TransferRules.ReturnCooldown,
+		Location:                transferLoc,
+	}
 
 	employeeRepo := postgres.NewEmployeeRepository(storage)
-	employeeService := employee.NewEmployeeService(employeeRepo)
+	employeeService := employee.NewEmployeeService(employeeRepo, shopService, employee.RegistrationPolicy{
+		Allowlist:  cfg.RegisterAllowlist,
+		InviteOnly: cfg.RegisterInviteOnly,
+	}, welcome, rules, cfg.Roles)
+	if err := employeeService.SeedRoles(context.Background()); err != nil {
+		log.Error("failed to seed roles", "error", err)
+		os.Exit(1)
+	}
+
+	orderRepo := postgres.NewOrderRepository(storage)
+	orderService := order.NewOrderService(orderRepo, cfg.OrderCancelWindow)
+
+	cartRepo := postgres.NewCartRepository(storage)
+	cartService := cart.NewCartService(cartRepo, shopService, employeeService)
+
+	ledgerRepo := postgres.NewLedgerRepository(storage)
+	ledgerService := ledger.NewLedgerService(ledgerRepo)
 
 	transferRepo := postgres.NewTransferRepository(storage)
 	transferService := transfer.NewTransferService(transferRepo)
 
-	r := chi.NewRouter()
-	r.Use(middleware.RequestID)
-	r.Use(mwlogger.New(log)) // middleware with our logger
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.URLFormat) // strong coherence with chi, might want to refactor in future
-
-	infoHandler := handlers.NewInfoHandler(employeeService, transferService, log)
-	coinHandler := handlers.NewCoinHandler(employeeService, transferService, valid, log)
-	shopHandler := handlers.NewShopHandler(employeeService, transferService, shop, log)
-	authHandler := handlers.NewAuthHandler(employeeService, valid, log)
-
-	r.Route("/api", func(r chi.Router) {
-		r.Use(mwauth.Auth)
-		r.HandleFunc("/info", infoHandler.Handle)       // GET
-		r.HandleFunc("/sendCoin", coinHandler.Handle)   // POST
-		r.HandleFunc("/buy/{item}", shopHandler.Handle) // GET
+	idempotencyRepo := postgres.NewIdempotencyRepository(storage)
+	idempotencyService := idempotency.NewIdempotencyService(idempotencyRepo, cfg.IdempotencyTTL)
+
+	providers := make([]identity.Provider, 0, len(cfg.OIDC))
+	for _, c := range cfg.OIDC {
+		providers = append(providers, oidc.NewProvider(c, &http.Client{Timeout: 10 * time.Second}))
+	}
+	identityRepo := postgres.NewIdentityRepository(storage)
+	identityService := identity.NewIdentityService(identityRepo, employeeService, providers...)
+
+	sessionRepo := postgres.NewSessionRepository(storage)
+	sessionService := session.NewSessionService(sessionRepo, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+
+	allowanceRepo := postgres.NewAllowanceRepository(storage)
+	allowanceService, err := allowance.NewAllowanceService(allowanceRepo, cfg.Allowance)
+	if err != nil {
+		log.Error("failed to set up allowances", "error", err)
+		os.Exit(1)
+	}
+
+	lotRepo := postgres.NewLotRepository(storage)
+	lotService, err := lot.NewLotService(lotRepo, cfg.CoinLifetimeMonths, cfg.CoinExpiryNotice)
+	if err != nil {
+		log.Error("failed to set up coin expiry", "error", err)
+		os.Exit(1)
+	}
+
+	schedulerRepo := postgres.NewSchedulerRepository(storage)
+	schedulerService := scheduler.NewSchedulerService(schedulerRepo, time.Minute, log)
+	err = setupJobs(schedulerService, cfg.Jobs, []job{
+		{"monthly_allowance", cfg.Jobs.MonthlyAllowance, func(ctx context.Context, scheduledAt time.Time) (int64, error) {
+			return allowanceService.CreditMonthly(ctx, scheduledAt)
+		}},
+		// grants held back under an earlier policy still vest after vesting was turned off
+		{"vest_welcome_grants", cfg.Jobs.VestWelcomeGrants, ignoreSlot(employeeService.VestWelcomeGrants)},
+		{"expire_coins", cfg.Jobs.ExpireCoins, ignoreSlot(lotService.Expire)},
+		{"purge_idempotency_keys", cfg.Jobs.PurgeIdempotencyKeys, ignoreSlot(idempotencyService.Purge)},
+		{"purge_sessions", cfg.Jobs.PurgeSessions, ignoreSlot(sessionService.Purge)},
+	}, log)
+	if err != nil {
+		log.Error("failed to set up jobs", "error", err)
+		os.Exit(1)
+	}
+	go schedulerService.Start(context.Background())
+
+	r := newRouter(&api{
+		keys:        keys,
+		activity:    employeeService,
+		revocations: sessionService,
+		idempotency: idempotencyService,
+		log:         log,
+
+		info:            handlers.NewInfoHandler(employeeService, transferService, lotService, log),
+		coin:            handlers.NewCoinHandler(employeeService, valid, log),
+		shop:            handlers.NewShopHandler(employeeService, valid, log),
+		auth:            handlers.NewAuthHandler(employeeService, sessionService, identityService, keys, cfg.AuthAutoRegister, valid, log),
+		jwks:            handlers.NewJWKSHandler(keys, log),
+		items:           handlers.NewItemsHandler(shopService, log),
+		cart:            handlers.NewCartHandler(cartService, valid, log),
+		orders:          handlers.NewOrdersHandler(orderService, log),
+		ledger:          handlers.NewLedgerHandler(ledgerService, log),
+		transfers:       handlers.NewTransfersHandler(transferService, log),
+		adminItems:      handlers.NewAdminItemsHandler(shopService, valid, log),
+		adminEmployees:  handlers.NewAdminEmployeesHandler(employeeService, log),
+		adminIdentities: handlers.NewAdminIdentitiesHandler(identityService, valid, log),
+		adminCoins:      handlers.NewAdminCoinsHandler(ledgerService, valid, log),
 	})
-	r.HandleFunc("/api/auth", authHandler.Handle) // POST
 
 	server := server.NewServer(cfg, r)
 	err = server.Start(log)
@@ -73,6 +205,59 @@ func main() {
 	}
 }
 
+// job is a background job that reports how many rows it processed
+type job struct {
+	name     string
+	schedule string
+	run      func(ctx context.Context, scheduledAt time.Time) (int64, error)
+}
+
+// ignoreSlot adapts jobs that only care about the current time, like purging expired rows
+func ignoreSlot(run func(ctx context.Context) (int64, error)) func(ctx context.Context, scheduledAt time.Time) (int64, error) {
+	return func(ctx context.Context, _ time.Time) (int64, error) {
+		return run(ctx)
+	}
+}
+
+// setupJobs registers the jobs with their configured schedules, skipping disabled ones
+func setupJobs(schedulerService *scheduler.SchedulerService, cfg config.Jobs, jobs []job, log *slog.Logger) error {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return err
+	}
+
+	for _, j := range jobs {
+		if j.schedule == scheduler.Disabled {
+			log.Info("job is disabled", "job", j.name)
+			continue
+		}
+		schedule, err := scheduler.ParseSchedule(j.schedule, loc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", j.name, err)
+		}
+
+		err = schedulerService.Register(j.name, schedule, func(ctx context.Context, scheduledAt time.Time) error {
+			n, err := j.run(ctx, scheduledAt)
+			log.Debug("job processed rows", "job", j.name, "count", n)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setupKeys loads the JWT signing keys, only dev may run without configured ones
+func setupKeys(cfg *config.Config) (*jwtkeys.KeySet, error) {
+	if len(cfg.JWT.Keys) == 0 && cfg.Env == envDev {
+		return jwtkeys.Ephemeral()
+	}
+
+	return jwtkeys.NewKeySet(cfg.JWT.ActiveKey, cfg.JWT.Keys)
+}
+
 func setupLogger(env string) *slog.Logger {
 	var log *slog.Logger
 
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/wdsjk/avito-shop/internal/infra/storage/migrations"
+)
+
+var errMigrateUsage = errors.New("usage: shop migrate up|down|status")
+
+// runMigrate implements the `shop migrate up|down|status` subcommand
+func runMigrate(ctx context.Context, db *sql.DB, log *slog.Logger, args []string) error {
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("migrations applied", "versions", applied)
+	case "down":
+		version, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("migration reverted", "version", version)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			appliedAt := "pending"
+			if st.AppliedAt != nil {
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errMigrateUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/idempotency"
+	"github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers"
+	mwauth "github.com/wdsjk/avito-shop/internal/infra/transport/http/middleware/auth"
+	mwidempotency "github.com/wdsjk/avito-shop/internal/infra/transport/http/middleware/idempotency"
+	mwlogger "github.com/wdsjk/avito-shop/internal/infra/transport/http/middleware/logger"
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
+)
+
+// api is what the router serves and the middleware it needs
+type api struct {
+	keys        *jwtkeys.KeySet
+	activity    mwauth.ActivityChecker
+	revocations mwauth.RevocationChecker
+	idempotency *idempotency.IdempotencyService
+	log         *slog.Logger
+
+	info            *handlers.InfoHandler
+	coin            *handlers.CoinHandler
+	shop            *handlers.ShopHandler
+	auth            *handlers.AuthHandler
+	jwks            *handlers.JWKSHandler
+	items           *handlers.ItemsHandler
+	cart            *handlers.CartHandler
+	orders          *handlers.OrdersHandler
+	ledger          *handlers.LedgerHandler
+	transfers       *handlers.TransfersHandler
+	adminItems      *handlers.AdminItemsHandler
+	adminEmployees  *handlers.AdminEmployeesHandler
+	adminIdentities *handlers.AdminIdentitiesHandler
+	adminCoins      *handlers.AdminCoinsHandler
+}
+
+func newRouter(a *api) http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(mwlogger.New(a.log)) // middleware with our logger
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.URLFormat) // strong coherence with chi, might want to refactor in future
+
+	r.Route("/api", func(r chi.Router) {
+		r.Use(mwauth.New(a.keys, a.activity, a.revocations))
+		idempotent := mwidempotency.New(a.idempotency, a.log) // retries with the same Idempotency-Key run once
+
+		r.Post("/auth/logout", a.auth.Logout)
+
+		r.HandleFunc("/info", a.info.Handle)                        // GET
+		r.With(idempotent).HandleFunc("/sendCoin", a.coin.Handle)   // POST
+		r.With(idempotent).HandleFunc("/buy/{item}", a.shop.Handle) // GET, POST
+		r.Get("/items", a.items.Handle)
+
+		r.Get("/cart", a.cart.Get)
+		r.Post("/cart/items", a.cart.AddItem)
+		r.Delete("/cart/items", a.cart.RemoveItem)
+		r.With(idempotent).Post("/cart/checkout", a.cart.Checkout)
+
+		r.Get("/orders", a.orders.List)
+		r.Get("/orders/{id}", a.orders.Get)
+		r.Post("/orders/{id}/cancel", a.orders.Cancel)
+
+		r.Get("/transfers", a.transfers.Handle)
+
+		r.Route("/admin", func(r chi.Router) {
+			// auditors may look, only admins may touch
+			r.Use(mwauth.RequireRole(employee.RoleAdmin, employee.RoleAuditor))
+			r.Get("/ledger/reconcile", a.ledger.Reconcile)
+			r.Get("/employees/{name}/roles", a.adminEmployees.Roles)
+
+			r.Group(func(r chi.Router) {
+				r.Use(mwauth.RequireRole(employee.RoleAdmin))
+				r.Post("/items", a.adminItems.Create)
+				r.Put("/items/{item}", a.adminItems.Update)
+				r.Delete("/items/{item}", a.adminItems.Delete)
+				r.Post("/items/{item}/restock", a.adminItems.Restock)
+				r.Post("/orders/{id}/refund", a.orders.Refund)
+
+				r.Post("/employees/{name}/deactivate", a.adminEmployees.Deactivate)
+				r.Post("/employees/{name}/reactivate", a.adminEmployees.Reactivate)
+				r.Delete("/employees/{name}", a.adminEmployees.Delete)
+				r.Put("/employees/{name}/roles/{role}", a.adminEmployees.GrantRole)
+				r.Delete("/employees/{name}/roles/{role}", a.adminEmployees.RevokeRole)
+				r.Put("/employees/{name}/identities/{provider}", a.adminIdentities.Link)
+				r.Post("/invites", a.adminEmployees.CreateInvite)
+
+				r.With(idempotent).Post("/coins/adjustments", a.adminCoins.Adjust) // JSON or text/csv
+				r.With(idempotent).Post("/employees/{name}/coins", a.adminCoins.AdjustEmployee)
+			})
+		})
+	})
+	r.HandleFunc("/api/auth", a.auth.Handle) // POST
+	r.Post("/api/auth/refresh", a.auth.Refresh)
+	r.Post("/api/register", a.auth.Register)
+	r.Get("/api/auth/oidc/{provider}/login", a.auth.OIDCLogin)
+	r.Get("/api/auth/oidc/{provider}/callback", a.auth.OIDCCallback)
+	r.Get("/.well-known/jwks.json", a.jwks.Handle)
+
+	return r
+}
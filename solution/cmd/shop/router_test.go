@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers"
+	"github.com/wdsjk/avito-shop/internal/ledger"
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+// allowAll treats every employee as active and no token as revoked
+type allowAll struct{}
+
+func (allowAll) IsActive(context.Context, string) (bool, error)  { return true, nil }
+func (allowAll) IsRevoked(context.Context, string) (bool, error) { return false, nil }
+
+// ledgerRepo finds the ledger balanced
+type ledgerRepo struct {
+	ledger.Repository
+}
+
+func (ledgerRepo) Reconcile(context.Context) (*ledger.Reconciliation, error) {
+	return &ledger.Reconciliation{CheckedAt: time.Now()}, nil
+}
+
+// shopRepo restocks any item
+type shopRepo struct {
+	shop.Repository
+}
+
+func (shopRepo) Restock(_ context.Context, name string, quantity int) (*shop.Item, error) {
+	return &shop.Item{Name: name, Price: 1, Active: true, Stock: &quantity}, nil
+}
+
+func TestAdminRoutesRequireRole(t *testing.T) {
+	keys, err := jwtkeys.Ephemeral()
+	if err != nil {
+		t.Fatalf("generate keys: %v", err)
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// only the handlers behind the tested routes are wired
+	r := newRouter(&api{
+		keys:        keys,
+		activity:    allowAll{},
+		revocations: allowAll{},
+		log:         log,
+
+		ledger:     handlers.NewLedgerHandler(ledger.NewLedgerService(ledgerRepo{}), log),
+		adminItems: handlers.NewAdminItemsHandler(shop.NewShopService(shopRepo{}), validator.New(), log),
+	})
+
+	token := func(roles ...string) string {
+		signed, err := utils.GenerateJWT(keys, "alice", roles, "test-jti", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"missing token", http.MethodGet, "/api/admin/ledger/reconcile", "", http.StatusUnauthorized},
+		{"ordinary user reads", http.MethodGet, "/api/admin/ledger/reconcile", token(), http.StatusForbidden},
+		{"ordinary user writes", http.MethodPost, "/api/admin/items/pen/restock", token(), http.StatusForbidden},
+		{"auditor reads", http.MethodGet, "/api/admin/ledger/reconcile", token(employee.RoleAuditor), http.StatusOK},
+		{"auditor writes", http.MethodPost, "/api/admin/items/pen/restock", token(employee.RoleAuditor), http.StatusForbidden},
+		{"admin reads", http.MethodGet, "/api/admin/ledger/reconcile", token(employee.RoleAdmin), http.StatusOK},
+		{"admin writes", http.MethodPost, "/api/admin/items/pen/restock", token(employee.RoleAdmin), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"quantity": 1}`))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
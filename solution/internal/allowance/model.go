@@ -0,0 +1,22 @@
+package allowance
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidAmount = errors.New("allowance must be positive")
+
+// Allowance is the recurring grant an employee got for one period
+type Allowance struct {
+	Employee      string    `db:"employee"`
+	Period        time.Time `db:"period"` // first day of the month
+	Amount        int       `db:"amount"`
+	TransactionID int64     `db:"transaction_id"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// MonthOf returns the period of the month t falls into in t's location
+func MonthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,12 @@
+package allowance
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Credit gives amount to every active employee who didn't get the allowance for period yet
+	// and returns how many employees were credited
+	Credit(ctx context.Context, period time.Time, amount int) (int64, error)
+}
@@ -0,0 +1,25 @@
+package allowance
+
+import (
+	"context"
+	"time"
+)
+
+type AllowanceService struct {
+	repo   Repository
+	amount int
+}
+
+func NewAllowanceService(repo Repository, amount int) (*AllowanceService, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	return &AllowanceService{repo: repo, amount: amount}, nil
+}
+
+// CreditMonthly credits the allowance for the month scheduledAt falls into. Running it
+// again for the same month only credits employees that were missed, e.g. after a failure
+func (s *AllowanceService) CreditMonthly(ctx context.Context, scheduledAt time.Time) (int64, error) {
+	return s.repo.Credit(ctx, MonthOf(scheduledAt), s.amount)
+}
@@ -0,0 +1,31 @@
+package cart
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrEmptyCart   = errors.New("cart is empty")
+	ErrUnavailable = errors.New("cart has unavailable items")
+)
+
+type Item struct {
+	EmployeeName string    `db:"employee_name"`
+	Name         string    `db:"item"`
+	Quantity     int       `db:"quantity"`
+	AddedAt      time.Time `db:"added_at"`
+}
+
+// Line is a cart item priced against the current catalog
+type Line struct {
+	Name      string
+	Quantity  int
+	UnitPrice int  // 0 if the item is gone from the catalog
+	Available bool // false if the item can't be bought right now
+}
+
+type Cart struct {
+	Lines []*Line
+	Total int
+}
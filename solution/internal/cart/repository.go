@@ -0,0 +1,9 @@
+package cart
+
+import "context"
+
+type Repository interface {
+	AddItem(ctx context.Context, name, item string, quantity int) (*Item, error)
+	RemoveItem(ctx context.Context, name, item string, quantity int) error
+	GetItems(ctx context.Context, name string) ([]*Item, error)
+}
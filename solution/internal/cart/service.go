@@ -0,0 +1,95 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/order"
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+type CartService struct {
+	repo      Repository
+	shop      *shop.ShopService
+	employees *employee.EmployeeService
+}
+
+func NewCartService(repo Repository, shop *shop.ShopService, employees *employee.EmployeeService) *CartService {
+	return &CartService{repo: repo, shop: shop, employees: employees}
+}
+
+func (s *CartService) AddItem(ctx context.Context, name, itemName string, quantity int) (*Item, error) {
+	if quantity <= 0 || quantity > shop.MaxQuantity {
+		return nil, shop.ErrBadQuantity
+	}
+
+	if _, err := s.shop.GetActiveItem(ctx, itemName); err != nil {
+		return nil, err
+	}
+
+	return s.repo.AddItem(ctx, name, itemName, quantity)
+}
+
+// RemoveItem takes quantity units of the item out of the cart, 0 removes all of them
+func (s *CartService) RemoveItem(ctx context.Context, name, itemName string, quantity int) error {
+	if quantity < 0 {
+		return shop.ErrBadQuantity
+	}
+
+	return s.repo.RemoveItem(ctx, name, itemName, quantity)
+}
+
+// GetCart prices the cart against the current catalog
+func (s *CartService) GetCart(ctx context.Context, name string) (*Cart, error) {
+	items, err := s.repo.GetItems(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	catalog, err := s.shop.GetItems(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &Cart{Lines: make([]*Line, 0, len(items))}
+	for _, item := range items {
+		line := &Line{Name: item.Name, Quantity: item.Quantity}
+		if catalogItem, ok := catalog[item.Name]; ok {
+			line.UnitPrice = catalogItem.Price
+			line.Available = catalogItem.Available()
+		}
+		if line.Available {
+			cart.Total += line.UnitPrice * line.Quantity
+		}
+		cart.Lines = append(cart.Lines, line)
+	}
+
+	return cart, nil
+}
+
+// Checkout pays for the whole cart at once, it fails without buying anything
+// if any item can't be bought
+func (s *CartService) Checkout(ctx context.Context, name string) (*order.Order, error) {
+	cart, err := s.GetCart(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cart.Lines) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	lines := make([]*order.Line, 0, len(cart.Lines))
+	for _, line := range cart.Lines {
+		if !line.Available {
+			return nil, ErrUnavailable
+		}
+		lines = append(lines, &order.Line{Item: line.Name, Quantity: line.Quantity, UnitPrice: line.UnitPrice})
+	}
+
+	return s.employees.Checkout(ctx, name, lines)
+}
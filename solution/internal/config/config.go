@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/wdsjk/avito-shop/internal/infra/oidc"
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
 )
 
 type Config struct {
@@ -16,6 +18,38 @@ type Config struct {
 	DbName     string `yaml:"db_name" env:"DB_NAME" env-required:"true"`
 	DbHost     string `yaml:"db_host" env:"DB_HOST" env-required:"true"`
 	DbPort     string `yaml:"db_port" env:"DB_PORT" env-required:"true"`
+	// apply pending migrations when the server starts, disable to run them only via `shop migrate up`
+	MigrateOnStart bool `yaml:"migrate_on_start" env:"MIGRATE_ON_START" env-default:"true"`
+	// roles granted on startup or registration, as role -> employee names. Grants made
+	// through the API are kept, removing a name here doesn't revoke anything
+	Roles map[string][]string `yaml:"roles"`
+	// how long after a purchase the employee may still cancel it
+	OrderCancelWindow time.Duration `yaml:"order_cancel_window" env:"ORDER_CANCEL_WINDOW" env-default:"15m"`
+	// let /api/auth create an account on the first login with an unknown username,
+	// when disabled accounts are only created via POST /api/register
+	AuthAutoRegister bool `yaml:"auth_auto_register" env:"AUTH_AUTO_REGISTER" env-default:"true"`
+	// usernames allowed to register, empty allows anyone
+	RegisterAllowlist []string `yaml:"register_allowlist" env:"REGISTER_ALLOWLIST" env-separator:","`
+	// require an invite code issued by an admin to register
+	RegisterInviteOnly bool `yaml:"register_invite_only" env:"REGISTER_INVITE_ONLY" env-default:"false"`
+	WelcomeGrant       `yaml:"welcome_grant"`
+	// coins every active employee gets from the monthly_allowance job
+	Allowance int `yaml:"allowance" env:"ALLOWANCE" env-default:"100"`
+	// coins expire this many months after they were granted, coins sent to a colleague keep their age
+	CoinLifetimeMonths int `yaml:"coin_lifetime_months" env:"COIN_LIFETIME_MONTHS" env-default:"12"`
+	// how far ahead /api/info lists coins that are about to expire
+	CoinExpiryNotice time.Duration `yaml:"coin_expiry_notice" env:"COIN_EXPIRY_NOTICE" env-default:"720h"`
+	Jobs             `yaml:"jobs"`
+	JWT              `yaml:"jwt"`
+	// external identity providers employees may log in with instead of a password
+	OIDC []oidc.Config `yaml:"oidc"`
+	// lifetime of the JWT access tokens, keep it short since refreshing is cheap
+	AccessTokenTTL time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL" env-default:"15m"`
+	// lifetime of a refresh token, every refresh issues a new one
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" env-default:"720h"`
+	TransferRules   `yaml:"transfer_rules"`
+	// how long a response stored under an Idempotency-Key is replayed to retries
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" env:"IDEMPOTENCY_TTL" env-default:"24h"`
 }
 
 type HTTPServer struct {
@@ -24,6 +58,47 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env:"IDLE_TIMEOUT" env-default:"30s"`
 }
 
+// JWT lists the keys access tokens are signed with. Rotation: add the new key, make it active,
+// and give the previous one a retire_at of at least the access token TTL from now
+type JWT struct {
+	ActiveKey string           `yaml:"active_key" env:"JWT_ACTIVE_KEY"`
+	Keys      []jwtkeys.Config `yaml:"keys"`
+}
+
+// WelcomeGrant is the coins a new employee starts with. A department's amount wins over
+// the registration channel's (auth, register, oidc), which wins over the default amount
+type WelcomeGrant struct {
+	Amount      int            `yaml:"amount" env:"WELCOME_GRANT_AMOUNT" env-default:"1000"`
+	Channels    map[string]int `yaml:"channels"`
+	Departments map[string]int `yaml:"departments"`
+	// percent of the grant only credited once vest_after has passed since registration
+	VestingPercent int           `yaml:"vesting_percent" env:"WELCOME_GRANT_VESTING_PERCENT" env-default:"0"`
+	VestAfter      time.Duration `yaml:"vest_after" env:"WELCOME_GRANT_VEST_AFTER" env-default:"0s"`
+}
+
+// TransferRules limit what an employee may send to colleagues, zero turns a rule off.
+// Days start at midnight in the timezone
+type TransferRules struct {
+	MaxPerTransfer          int           `yaml:"max_per_transfer" env:"TRANSFER_MAX_PER_TRANSFER"`
+	MaxPerDay               int           `yaml:"max_per_day" env:"TRANSFER_MAX_PER_DAY"`
+	MaxPerCounterpartPerDay int           `yaml:"max_per_counterpart_per_day" env:"TRANSFER_MAX_PER_COUNTERPART_PER_DAY"`
+	Cooldown                time.Duration `yaml:"cooldown" env:"TRANSFER_COOLDOWN"`
+	// how long after receiving coins they can't be sent back to the same colleague
+	ReturnCooldown time.Duration `yaml:"return_cooldown" env:"TRANSFER_RETURN_COOLDOWN"`
+	Timezone       string        `yaml:"timezone" env:"TRANSFER_TIMEZONE" env-default:"UTC"`
+}
+
+// Jobs are the cron schedules of the background jobs: minute, hour, day of month, month and
+// day of week, or @hourly, @daily, @weekly and @monthly. "off" disables a job
+type Jobs struct {
+	Timezone             string `yaml:"timezone" env:"JOBS_TIMEZONE" env-default:"UTC"`
+	MonthlyAllowance     string `yaml:"monthly_allowance" env:"JOB_MONTHLY_ALLOWANCE" env-default:"@monthly"`
+	VestWelcomeGrants    string `yaml:"vest_welcome_grants" env:"JOB_VEST_WELCOME_GRANTS" env-default:"@hourly"`
+	ExpireCoins          string `yaml:"expire_coins" env:"JOB_EXPIRE_COINS" env-default:"@daily"`
+	PurgeIdempotencyKeys string `yaml:"purge_idempotency_keys" env:"JOB_PURGE_IDEMPOTENCY_KEYS" env-default:"@hourly"`
+	PurgeSessions        string `yaml:"purge_sessions" env:"JOB_PURGE_SESSIONS" env-default:"*/15 * * * *"`
+}
+
 func MustLoad() *Config {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
@@ -4,20 +4,74 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
+)
+
+// ShopAccount is the sentinel employee on the other side of legacy purchase rows, nobody may send coins to it
+const ShopAccount = ""
+
+const (
+	// RoleAdmin manages the catalog, employees and balances
+	RoleAdmin = "admin"
+	// RoleAuditor has read-only access to the admin API
+	RoleAuditor = "auditor"
+)
+
+// Roles lists every role that can be granted
+var Roles = []string{RoleAdmin, RoleAuditor}
+
+const (
+	StatusActive      = "active"
+	StatusDeactivated = "deactivated"
+	StatusDeleted     = "deleted"
 )
 
 var (
 	errTypeAssertion = errors.New("type assertion to []byte failed")
+
+	ErrInvalidAmount   = errors.New("amount must be positive")
+	ErrSelfTransfer    = errors.New("can't send coins to yourself")
+	ErrReservedAccount = errors.New("account can't receive coins")
+
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrDeactivated        = errors.New("account is deactivated")
+	ErrNotAllowed         = errors.New("registration is not allowed for this username")
+	ErrNameTaken          = errors.New("username is already taken")
+	ErrInvalidInvite      = errors.New("invalid invite code")
+	ErrStatusTransition   = errors.New("employee status can't be changed")
+	ErrUnknownRole        = errors.New("unknown role")
 )
 
 type Inventory map[string]int
 
 type Employee struct {
-	ID        int    `db:"id"`
-	Name      string `db:"name"`
-	Password  string `db:"password"`
-	Coins     int    `db:"coins"`
-	Inventory `db:"bought_items"`
+	ID         int    `db:"id"`
+	Name       string `db:"name"`
+	Password   string `db:"password"`
+	Coins      int    `db:"coins"`
+	Inventory  `db:"bought_items"`
+	Status     string    `db:"status"`
+	Department string    `db:"department"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func (e *Employee) Active() bool {
+	return e.Status == StatusActive
+}
+
+// RegistrationPolicy restricts who may create an account, the zero value lets anyone register
+type RegistrationPolicy struct {
+	Allowlist  []string // usernames allowed to register, empty allows any
+	InviteOnly bool     // an unused invite code issued by an admin is required
+}
+
+// Invite is a single use code an admin hands out to let someone register
+type Invite struct {
+	Code       string     `db:"code"`
+	Department string     `db:"department"` // department the employee registering with it joins
+	CreatedBy  string     `db:"created_by"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
 }
 
 func (i *Inventory) Scan(src interface{}) error {
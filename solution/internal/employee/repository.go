@@ -2,14 +2,32 @@ package employee
 
 import (
 	"context"
+	"time"
 
+	"github.com/wdsjk/avito-shop/internal/order"
 	"github.com/wdsjk/avito-shop/internal/shop"
 	"github.com/wdsjk/avito-shop/internal/transfer"
 )
 
 type Repository interface {
-	SaveEmployee(ctx context.Context, name, password string) (string, error)
+	// SaveEmployee creates an active employee and credits the welcome grant, a non-empty
+	// invite is consumed in the same transaction
+	SaveEmployee(ctx context.Context, reg *Registration) (string, error)
 	GetEmployee(ctx context.Context, name string) (*Employee, error)
-	BuyItem(ctx context.Context, name, item string, shop shop.Shop, t *transfer.TransferService) error
-	TransferCoins(ctx context.Context, sender, receiver string, amount int, t *transfer.TransferService) error
+	// GetStatus returns an empty status for an unknown employee
+	GetStatus(ctx context.Context, name string) (string, error)
+	// SetStatus moves the employee from one of the from statuses to status
+	SetStatus(ctx context.Context, name, status string, from ...string) (*Employee, error)
+	SaveInvite(ctx context.Context, invite *Invite) error
+	// GetInvite returns ErrInvalidInvite for a code that is unknown, used or expired
+	GetInvite(ctx context.Context, code string) (*Invite, error)
+	// VestWelcomeGrants credits the held back welcome grants that are due at now
+	VestWelcomeGrants(ctx context.Context, now time.Time) (int64, error)
+	GetRoles(ctx context.Context, name string) ([]string, error)
+	GrantRole(ctx context.Context, name, role, grantedBy string) error
+	RevokeRole(ctx context.Context, name, role string) error
+	BuyItem(ctx context.Context, name string, item *shop.Item, quantity int) (*order.Order, error)
+	// TransferCoins returns a *transfer.RuleViolation if the rules don't allow the transfer
+	TransferCoins(ctx context.Context, sender, receiver string, amount int, note transfer.Note, rules transfer.Rules) error
+	Checkout(ctx context.Context, name string, lines []*order.Line) (*order.Order, error)
 }
@@ -2,21 +2,219 @@ package employee
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/wdsjk/avito-shop/internal/order"
 	"github.com/wdsjk/avito-shop/internal/shop"
 	"github.com/wdsjk/avito-shop/internal/transfer"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type EmployeeService struct {
-	repo Repository
+	repo    Repository
+	shop    *shop.ShopService
+	policy  RegistrationPolicy
+	welcome WelcomePolicy
+	rules   transfer.Rules
+	seeds   map[string][]string // employee name -> roles granted from config
 }
 
-func NewEmployeeService(repo Repository) *EmployeeService {
-	return &EmployeeService{repo: repo}
+// NewEmployeeService takes role seeds as role -> employee names, the way they are configured
+func NewEmployeeService(
+	repo Repository,
+	shop *shop.ShopService,
+	policy RegistrationPolicy,
+	welcome WelcomePolicy,
+	rules transfer.Rules,
+	roleSeeds map[string][]string,
+) *EmployeeService {
+	seeds := make(map[string][]string)
+	for role, names := range roleSeeds {
+		for _, name := range names {
+			seeds[name] = append(seeds[name], role)
+		}
+	}
+
+	departments := make(map[string]int, len(welcome.Departments))
+	for department, amount := range welcome.Departments {
+		department, _ = NormalizeDepartment(department)
+		departments[department] = amount
+	}
+	welcome.Departments = departments
+
+	return &EmployeeService{repo: repo, shop: shop, policy: policy, welcome: welcome, rules: rules, seeds: seeds}
+}
+
+// SaveEmployee registers a new employee if the registration policy lets them in,
+// the department of the invite decides their welcome grant
+func (s *EmployeeService) SaveEmployee(ctx context.Context, name, password, invite, channel string) (string, error) {
+	if strings.TrimSpace(name) == ShopAccount {
+		return "", ErrReservedAccount
+	}
+	if len(s.policy.Allowlist) > 0 && !slices.Contains(s.policy.Allowlist, name) {
+		return "", ErrNotAllowed
+	}
+	if s.policy.InviteOnly && invite == "" {
+		return "", ErrInvalidInvite
+	}
+
+	var department string
+	if invite != "" {
+		inv, err := s.repo.GetInvite(ctx, invite)
+		if err != nil {
+			return "", err
+		}
+		department = inv.Department
+	}
+
+	name, err := s.repo.SaveEmployee(ctx, &Registration{
+		Name:       name,
+		Password:   password,
+		Invite:     invite,
+		Department: department,
+		Channel:    channel,
+		Grant:      s.welcome.Grant(channel, department, time.Now()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return name, s.grantSeeded(ctx, name)
+}
+
+// Provision creates an employee for an identity vouched for by an external provider, the
+// registration policy doesn't apply and the password can't be used to log in. A name that
+// is taken is ErrNameTaken, the identity may not claim somebody else's account
+func (s *EmployeeService) Provision(ctx context.Context, name, department string) error {
+	if strings.TrimSpace(name) == ShopAccount {
+		return ErrReservedAccount
+	}
+
+	status, err := s.repo.GetStatus(ctx, name)
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		return ErrNameTaken
+	}
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return err
+	}
+	department, err = NormalizeDepartment(department)
+	if err != nil {
+		// an odd claim shouldn't lock the employee out, they just get the default grant
+		department = ""
+	}
+
+	_, err = s.repo.SaveEmployee(ctx, &Registration{
+		Name:       name,
+		Password:   hex.EncodeToString(password),
+		Department: department,
+		Channel:    ChannelOIDC,
+		Grant:      s.welcome.Grant(ChannelOIDC, department, time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.grantSeeded(ctx, name)
+}
+
+// Authenticate checks the employee's password, deleted employees can't log in at all
+func (s *EmployeeService) Authenticate(ctx context.Context, name, password string) (*Employee, error) {
+	emp, err := s.repo.GetEmployee(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if emp.Status == StatusDeleted {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(emp.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	// the status is only revealed to someone who knows the password
+	if !emp.Active() {
+		return nil, ErrDeactivated
+	}
+
+	return emp, nil
 }
 
-func (s *EmployeeService) SaveEmployee(ctx context.Context, name string, password string) (string, error) {
-	return s.repo.SaveEmployee(ctx, name, password)
+// IsActive reports whether the employee exists and may use the API
+func (s *EmployeeService) IsActive(ctx context.Context, name string) (bool, error) {
+	status, err := s.repo.GetStatus(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	return status == StatusActive, nil
+}
+
+func (s *EmployeeService) Deactivate(ctx context.Context, name string) (*Employee, error) {
+	if name == ShopAccount {
+		return nil, ErrReservedAccount
+	}
+
+	return s.repo.SetStatus(ctx, name, StatusDeactivated, StatusActive, StatusDeactivated)
+}
+
+func (s *EmployeeService) Reactivate(ctx context.Context, name string) (*Employee, error) {
+	if name == ShopAccount {
+		return nil, ErrReservedAccount
+	}
+
+	return s.repo.SetStatus(ctx, name, StatusActive, StatusDeactivated, StatusActive)
+}
+
+// Delete closes the account for good, the employee's history is kept but they can't log in or receive coins
+func (s *EmployeeService) Delete(ctx context.Context, name string) (*Employee, error) {
+	if name == ShopAccount {
+		return nil, ErrReservedAccount
+	}
+
+	return s.repo.SetStatus(ctx, name, StatusDeleted, StatusActive, StatusDeactivated, StatusDeleted)
+}
+
+// CreateInvite issues a single use invite code, a zero ttl never expires
+func (s *EmployeeService) CreateInvite(ctx context.Context, admin, department string, ttl time.Duration) (*Invite, error) {
+	department, err := NormalizeDepartment(department)
+	if err != nil {
+		return nil, err
+	}
+
+	code := make([]byte, 16)
+	if _, err := rand.Read(code); err != nil {
+		return nil, err
+	}
+
+	invite := &Invite{
+		Code:       hex.EncodeToString(code),
+		Department: department,
+		CreatedBy:  admin,
+		CreatedAt:  time.Now(),
+	}
+	if ttl > 0 {
+		expiresAt := invite.CreatedAt.Add(ttl)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.SaveInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// VestWelcomeGrants credits the held back parts of welcome grants that are due
+func (s *EmployeeService) VestWelcomeGrants(ctx context.Context) (int64, error) {
+	return s.repo.VestWelcomeGrants(ctx, time.Now())
 }
 
 func (s *EmployeeService) GetEmployee(ctx context.Context, name string) (*Employee, error) {
@@ -28,10 +226,88 @@ func (s *EmployeeService) GetEmployee(ctx context.Context, name string) (*Employ
 	return employee, nil
 }
 
-func (s *EmployeeService) BuyItem(ctx context.Context, name, item string, shop shop.Shop, t *transfer.TransferService) error {
-	return s.repo.BuyItem(ctx, name, item, shop, t)
+// BuyItem charges price*quantity for the item, the whole purchase fails if any unit can't be paid for
+func (s *EmployeeService) BuyItem(ctx context.Context, name, itemName string, quantity int) (*order.Order, error) {
+	if quantity <= 0 || quantity > shop.MaxQuantity {
+		return nil, shop.ErrBadQuantity
+	}
+
+	item, err := s.shop.GetActiveItem(ctx, itemName)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.BuyItem(ctx, name, item, quantity)
 }
 
-func (s *EmployeeService) TransferCoins(ctx context.Context, sender, receiver string, amount int, t *transfer.TransferService) error {
-	return s.repo.TransferCoins(ctx, sender, receiver, amount, t)
+func (s *EmployeeService) TransferCoins(ctx context.Context, sender, receiver string, amount int, message, tag string) error {
+	switch {
+	case amount <= 0:
+		return ErrInvalidAmount
+	case strings.TrimSpace(receiver) == ShopAccount:
+		return ErrReservedAccount
+	case receiver == sender:
+		return ErrSelfTransfer
+	}
+
+	note, err := transfer.NewNote(message, tag)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.TransferCoins(ctx, sender, receiver, amount, note, s.rules)
+}
+
+// Checkout pays for the lines in a single order and takes them out of the employee's cart
+func (s *EmployeeService) Checkout(ctx context.Context, name string, lines []*order.Line) (*order.Order, error) {
+	return s.repo.Checkout(ctx, name, lines)
+}
+
+func (s *EmployeeService) GetRoles(ctx context.Context, name string) ([]string, error) {
+	return s.repo.GetRoles(ctx, name)
+}
+
+func (s *EmployeeService) GrantRole(ctx context.Context, name, role, grantedBy string) error {
+	if !slices.Contains(Roles, role) {
+		return ErrUnknownRole
+	}
+
+	return s.repo.GrantRole(ctx, name, role, grantedBy)
+}
+
+func (s *EmployeeService) RevokeRole(ctx context.Context, name, role string) error {
+	if !slices.Contains(Roles, role) {
+		return ErrUnknownRole
+	}
+
+	return s.repo.RevokeRole(ctx, name, role)
+}
+
+// SeedRoles grants the configured roles to employees that already exist,
+// the rest get theirs when they register
+func (s *EmployeeService) SeedRoles(ctx context.Context) error {
+	for name := range s.seeds {
+		status, err := s.repo.GetStatus(ctx, name)
+		if err != nil {
+			return err
+		}
+		if status == "" {
+			continue
+		}
+		if err := s.grantSeeded(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *EmployeeService) grantSeeded(ctx context.Context, name string) error {
+	for _, role := range s.seeds[name] {
+		if err := s.GrantRole(ctx, name, role, "config"); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,54 @@
+package employee
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wdsjk/avito-shop/internal/transfer"
+)
+
+// transferRepo records transfers, the methods it doesn't override panic through the nil Repository
+type transferRepo struct {
+	Repository
+	transfers int
+}
+
+func (r *transferRepo) TransferCoins(context.Context, string, string, int, transfer.Note, transfer.Rules) error {
+	r.transfers++
+	return nil
+}
+
+func TestTransferCoinsValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		receiver string
+		amount   int
+		err      error
+	}{
+		{"zero amount", "bob", 0, ErrInvalidAmount},
+		{"negative amount", "bob", -5, ErrInvalidAmount},
+		{"self transfer", "alice", 10, ErrSelfTransfer},
+		{"shop account", ShopAccount, 10, ErrReservedAccount},
+		{"blank name", "  ", 10, ErrReservedAccount},
+		{"valid", "bob", 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &transferRepo{}
+			s := NewEmployeeService(repo, nil, RegistrationPolicy{}, WelcomePolicy{}, transfer.Rules{}, nil)
+
+			err := s.TransferCoins(context.Background(), "alice", tt.receiver, tt.amount, "", "")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			want := 0
+			if tt.err == nil {
+				want = 1
+			}
+			if repo.transfers != want {
+				t.Errorf("%d transfers reached the repository, want %d", repo.transfers, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package employee
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Registration channels an employee account can be created through
+const (
+	ChannelAuth     = "auth"     // first login at /api/auth with auto registration on
+	ChannelRegister = "register" // POST /api/register
+	ChannelOIDC     = "oidc"     // first login with an external identity provider
+)
+
+// MaxDepartmentLength matches employees.department
+const MaxDepartmentLength = 64
+
+var (
+	ErrInvalidDepartment = errors.New("invalid department")
+	ErrInvalidWelcome    = errors.New("invalid welcome grant policy")
+)
+
+// WelcomePolicy decides how many coins a new employee starts with. The most specific
+// amount wins: the department's, then the registration channel's, then Amount
+type WelcomePolicy struct {
+	Amount      int
+	Channels    map[string]int
+	Departments map[string]int
+	// percent of the grant held back until VestAfter has passed since registration, zero grants everything upfront
+	VestingPercent int
+	VestAfter      time.Duration
+}
+
+// WelcomeGrant is what a new employee gets, Amount right away and Vesting at VestAt
+type WelcomeGrant struct {
+	Amount  int
+	Vesting int
+	VestAt  time.Time
+}
+
+// Registration is a new employee and the welcome grant they start with
+type Registration struct {
+	Name       string
+	Password   string
+	Invite     string // consumed together with the registration if set
+	Department string
+	Channel    string
+	Grant      WelcomeGrant
+}
+
+func (p WelcomePolicy) Validate() error {
+	if p.Amount < 0 || p.VestingPercent < 0 || p.VestingPercent > 100 {
+		return ErrInvalidWelcome
+	}
+	if p.VestingPercent > 0 && p.VestAfter <= 0 {
+		return ErrInvalidWelcome
+	}
+	for _, amounts := range []map[string]int{p.Channels, p.Departments} {
+		for _, amount := range amounts {
+			if amount < 0 {
+				return ErrInvalidWelcome
+			}
+		}
+	}
+
+	return nil
+}
+
+// Grant works out the welcome grant of an employee registered at now
+func (p WelcomePolicy) Grant(channel, department string, now time.Time) WelcomeGrant {
+	total := p.Amount
+	if amount, ok := p.Channels[channel]; ok {
+		total = amount
+	}
+	if amount, ok := p.Departments[department]; ok && department != "" {
+		total = amount
+	}
+
+	vesting := total * p.VestingPercent / 100
+	grant := WelcomeGrant{Amount: total - vesting, Vesting: vesting}
+	if vesting > 0 {
+		grant.VestAt = now.Add(p.VestAfter)
+	}
+
+	return grant
+}
+
+// NormalizeDepartment trims the department and lowercases it, so config keys match however it was spelled
+func NormalizeDepartment(department string) (string, error) {
+	department = strings.ToLower(strings.TrimSpace(department))
+	if utf8.RuneCountInString(department) > MaxDepartmentLength {
+		return "", ErrInvalidDepartment
+	}
+
+	return department, nil
+}
@@ -0,0 +1,33 @@
+package idempotency
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	HeaderKey    = "Idempotency-Key"
+	MaxKeyLength = 255
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid idempotency key")
+	ErrKeyReused  = errors.New("idempotency key was used with a different request")
+	ErrInProgress = errors.New("request with this idempotency key is still in progress")
+)
+
+// Record is the stored outcome of the first request sent with a key
+type Record struct {
+	EmployeeName string    `db:"employee_name"`
+	Key          string    `db:"key"`
+	RequestHash  string    `db:"request_hash"`
+	StatusCode   int       `db:"status_code"` // zero while the request is still being handled
+	ContentType  string    `db:"content_type"`
+	Body         []byte    `db:"body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+func (r *Record) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,15 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Reserve claims the key for a new request, it returns the record already holding
+	// the key (nil if the claim succeeded). Expired records are claimed over
+	Reserve(ctx context.Context, name, key, requestHash string, expiresAt time.Time) (*Record, error)
+	Complete(ctx context.Context, name, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, name, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,66 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+type IdempotencyService struct {
+	repo Repository
+	ttl  time.Duration
+}
+
+func NewIdempotencyService(repo Repository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+// Begin claims key for the employee's request. A nil record means the request should be
+// executed and then passed to Complete or Release, otherwise the stored response must be replayed
+func (s *IdempotencyService) Begin(ctx context.Context, name, key string, request []byte) (*Record, error) {
+	if key == "" || len(key) > MaxKeyLength {
+		return nil, ErrInvalidKey
+	}
+
+	hash := HashRequest(request)
+	rec, err := s.repo.Reserve(ctx, name, key, hash, time.Now().Add(s.ttl))
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, nil
+	}
+
+	if rec.RequestHash != hash {
+		return nil, ErrKeyReused
+	}
+	if !rec.Completed() {
+		return nil, ErrInProgress
+	}
+
+	return rec, nil
+}
+
+// Complete stores the response so that retries with the same key get it back
+func (s *IdempotencyService) Complete(ctx context.Context, name, key string, statusCode int, contentType string, body []byte) error {
+	return s.repo.Complete(ctx, name, key, statusCode, contentType, body)
+}
+
+// Release frees the key after a failure that should not be replayed, so the client may retry
+func (s *IdempotencyService) Release(ctx context.Context, name, key string) error {
+	return s.repo.Release(ctx, name, key)
+}
+
+// Purge removes records older than the TTL
+func (s *IdempotencyService) Purge(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx)
+}
+
+func HashRequest(request []byte) string {
+	sum := sha256.Sum256(request)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,40 @@
+package identity
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// StateTTL is how long a user has to finish logging in at the provider
+	StateTTL = 10 * time.Minute
+	// MaxSubjectLength matches employee_identities.subject
+	MaxSubjectLength = 255
+)
+
+var (
+	ErrUnknownProvider  = errors.New("unknown identity provider")
+	ErrInvalidState     = errors.New("invalid or expired login state")
+	ErrInvalidToken     = errors.New("invalid id token")
+	ErrNoUsername       = errors.New("identity has no usable username")
+	ErrIdentityConflict = errors.New("identity can't be linked to the employee")
+	ErrInvalidSubject   = errors.New("invalid identity subject")
+)
+
+// Identity is a user as asserted by an external provider
+type Identity struct {
+	Provider   string
+	Subject    string // stable id of the user at the provider
+	Email      string
+	Username   string // employee name the identity maps to on first login
+	Department string // decides the welcome grant of an employee provisioned on first login
+}
+
+// LoginState keeps what the callback needs to finish an authorization code login
+type LoginState struct {
+	State        string    `db:"state"`
+	Provider     string    `db:"provider"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
@@ -0,0 +1,12 @@
+package identity
+
+import "context"
+
+// Provider is an external identity provider the password flow can be swapped for
+type Provider interface {
+	Name() string
+	// AuthCodeURL is where the user is sent to log in, codeChallenge is the PKCE S256 challenge
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange redeems the code returned to the callback and verifies the identity it stands for
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
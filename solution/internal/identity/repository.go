@@ -0,0 +1,12 @@
+package identity
+
+import "context"
+
+type Repository interface {
+	SaveState(ctx context.Context, state *LoginState) error
+	// TakeState deletes and returns an unexpired state, nil if there is none
+	TakeState(ctx context.Context, state string) (*LoginState, error)
+	// FindEmployee returns the employee linked to the identity, empty if it isn't linked yet
+	FindEmployee(ctx context.Context, provider, subject string) (string, error)
+	Link(ctx context.Context, identity *Identity, name string) error
+}
@@ -0,0 +1,144 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/employee"
+)
+
+type IdentityService struct {
+	repo      Repository
+	employees *employee.EmployeeService
+	providers map[string]Provider
+}
+
+func NewIdentityService(repo Repository, employees *employee.EmployeeService, providers ...Provider) *IdentityService {
+	s := &IdentityService{
+		repo:      repo,
+		employees: employees,
+		providers: make(map[string]Provider, len(providers)),
+	}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+	}
+
+	return s
+}
+
+// Begin starts a login at the provider and returns the URL to send the user to
+func (s *IdentityService) Begin(ctx context.Context, providerName string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	state := &LoginState{Provider: providerName, ExpiresAt: time.Now().Add(StateTTL)}
+	for _, v := range []*string{&state.State, &state.Nonce, &state.CodeVerifier} {
+		token, err := randomToken()
+		if err != nil {
+			return "", err
+		}
+		*v = token
+	}
+
+	if err := s.repo.SaveState(ctx, state); err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(ctx, state.State, state.Nonce, codeChallenge(state.CodeVerifier))
+}
+
+// Complete finishes the login and returns the employee the identity belongs to. An identity
+// seen for the first time gets a new employee named after its username. The username is
+// neither unique nor stable at the provider, so it never links to an existing employee,
+// that takes an admin calling Link
+func (s *IdentityService) Complete(ctx context.Context, providerName, state, code string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	login, err := s.repo.TakeState(ctx, state)
+	if err != nil {
+		return "", err
+	}
+	if login == nil || login.Provider != providerName {
+		return "", ErrInvalidState
+	}
+
+	ident, err := provider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return "", err
+	}
+
+	name, err := s.repo.FindEmployee(ctx, providerName, ident.Subject)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		if ident.Username == "" {
+			return "", ErrNoUsername
+		}
+		if err := s.employees.Provision(ctx, ident.Username, ident.Department); err != nil {
+			if errors.Is(err, employee.ErrNameTaken) {
+				return "", ErrIdentityConflict
+			}
+			return "", err
+		}
+		if err := s.repo.Link(ctx, ident, ident.Username); err != nil {
+			return "", err
+		}
+		name = ident.Username
+	}
+
+	active, err := s.employees.IsActive(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if !active {
+		return "", employee.ErrDeactivated
+	}
+
+	return name, nil
+}
+
+// Link lets an existing employee log in with their identity at the provider from now on
+func (s *IdentityService) Link(ctx context.Context, providerName, subject, name string) error {
+	if _, ok := s.providers[providerName]; !ok {
+		return ErrUnknownProvider
+	}
+	if subject == "" || len(subject) > MaxSubjectLength {
+		return ErrInvalidSubject
+	}
+	if strings.TrimSpace(name) == employee.ShopAccount {
+		return employee.ErrReservedAccount
+	}
+
+	// the employee must exist, a deactivated one is only turned away at login
+	if _, err := s.employees.GetEmployee(ctx, name); err != nil {
+		return err
+	}
+
+	return s.repo.Link(ctx, &Identity{Provider: providerName, Subject: subject}, name)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeChallenge derives the PKCE S256 challenge from the verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
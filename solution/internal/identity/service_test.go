@@ -0,0 +1,107 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/transfer"
+)
+
+// employeeRepo knows employees by name and status, the methods it doesn't override
+// aren't used by logins and panic through the nil Repository
+type employeeRepo struct {
+	employee.Repository
+	statuses map[string]string
+}
+
+func (r *employeeRepo) GetStatus(_ context.Context, name string) (string, error) {
+	return r.statuses[name], nil
+}
+
+func (r *employeeRepo) GetEmployee(_ context.Context, name string) (*employee.Employee, error) {
+	if r.statuses[name] == "" {
+		return nil, errors.New("employee not found")
+	}
+	return &employee.Employee{Name: name, Status: r.statuses[name]}, nil
+}
+
+func (r *employeeRepo) SaveEmployee(_ context.Context, reg *employee.Registration) (string, error) {
+	r.statuses[reg.Name] = employee.StatusActive
+	return reg.Name, nil
+}
+
+// identityRepo accepts any login state and keeps links in a map
+type identityRepo struct {
+	links map[string]string // subject -> employee
+}
+
+func (r *identityRepo) SaveState(context.Context, *LoginState) error {
+	return nil
+}
+
+func (r *identityRepo) TakeState(_ context.Context, state string) (*LoginState, error) {
+	return &LoginState{State: state, Provider: "idp"}, nil
+}
+
+func (r *identityRepo) FindEmployee(_ context.Context, _, subject string) (string, error) {
+	return r.links[subject], nil
+}
+
+func (r *identityRepo) Link(_ context.Context, ident *Identity, name string) error {
+	r.links[ident.Subject] = name
+	return nil
+}
+
+// provider vouches for whatever identity it holds
+type provider struct {
+	ident *Identity
+}
+
+func (p *provider) Name() string { return "idp" }
+
+func (p *provider) AuthCodeURL(context.Context, string, string, string) (string, error) {
+	return "", nil
+}
+
+func (p *provider) Exchange(context.Context, string, string, string) (*Identity, error) {
+	return p.ident, nil
+}
+
+func TestFirstLoginDoesNotTakeOverExistingEmployee(t *testing.T) {
+	employees := &employeeRepo{statuses: map[string]string{"admin": employee.StatusActive}}
+	idents := &identityRepo{links: make(map[string]string)}
+	idp := &provider{}
+	s := NewIdentityService(idents, employee.NewEmployeeService(
+		employees, nil, employee.RegistrationPolicy{}, employee.WelcomePolicy{}, transfer.Rules{}, nil,
+	), idp)
+	ctx := context.Background()
+
+	idp.ident = &Identity{Provider: "idp", Subject: "mallory", Username: "admin"}
+	if _, err := s.Complete(ctx, "idp", "state", "code"); !errors.Is(err, ErrIdentityConflict) {
+		t.Fatalf("login claiming an existing name: got %v, want %v", err, ErrIdentityConflict)
+	}
+	if name, ok := idents.links["mallory"]; ok {
+		t.Fatalf("identity was linked to %q", name)
+	}
+
+	idp.ident = &Identity{Provider: "idp", Subject: "carol-at-idp", Username: "carol"}
+	name, err := s.Complete(ctx, "idp", "state", "code")
+	if err != nil || name != "carol" {
+		t.Fatalf("login with a new name: got %q, %v, want carol", name, err)
+	}
+	if employees.statuses["carol"] != employee.StatusActive {
+		t.Errorf("carol was not provisioned")
+	}
+
+	// once an admin links the identity, whatever username it claims it logs in as the admin
+	if err := s.Link(ctx, "idp", "admin-at-idp", "admin"); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	idp.ident = &Identity{Provider: "idp", Subject: "admin-at-idp", Username: "someone-else"}
+	name, err = s.Complete(ctx, "idp", "state", "code")
+	if err != nil || name != "admin" {
+		t.Fatalf("login with a linked identity: got %q, %v, want admin", name, err)
+	}
+}
@@ -0,0 +1,23 @@
+-- tables may already exist on databases created before migrations were introduced
+CREATE TABLE IF NOT EXISTS employees (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL UNIQUE,
+	password VARCHAR(100) NOT NULL,
+	coins INT CHECK (coins > -1),
+	bought_items JSONB NOT NULL
+);
+
+-- basically a shop reference into the employees table
+INSERT INTO employees (name, password, coins, bought_items)
+SELECT name, password, coins, bought_items
+FROM (VALUES
+	('', '', 0, '{}'::JSONB)
+) AS new_employee(name, password, coins, bought_items)
+WHERE NOT EXISTS (SELECT 1 FROM employees LIMIT 1);
+
+CREATE TABLE IF NOT EXISTS transfers (
+	id SERIAL PRIMARY KEY,
+	sender_name VARCHAR(50) REFERENCES employees(name),
+	receiver_name VARCHAR(50) REFERENCES employees(name),
+	amount INT CHECK (amount > 0) NOT NULL
+);
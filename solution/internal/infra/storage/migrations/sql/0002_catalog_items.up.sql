@@ -0,0 +1,22 @@
+CREATE TABLE catalog_items (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL UNIQUE,
+	price INT NOT NULL CHECK (price > 0),
+	description TEXT NOT NULL DEFAULT '',
+	active BOOLEAN NOT NULL DEFAULT true,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- items that used to be hardcoded in shop.NewShop
+INSERT INTO catalog_items (name, price) VALUES
+	('t-shirt', 80),
+	('cup', 20),
+	('book', 50),
+	('pen', 10),
+	('powerbank', 200),
+	('hoody', 300),
+	('umbrella', 200),
+	('socks', 10),
+	('wallet', 50),
+	('pink-hoody', 500);
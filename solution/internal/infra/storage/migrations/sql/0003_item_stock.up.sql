@@ -0,0 +1,2 @@
+-- NULL stock means the item is unlimited, as every item was before
+ALTER TABLE catalog_items ADD COLUMN stock INT CHECK (stock >= 0);
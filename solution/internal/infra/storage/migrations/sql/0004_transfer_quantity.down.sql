@@ -0,0 +1,2 @@
+ALTER TABLE transfers DROP COLUMN IF EXISTS quantity;
+ALTER TABLE transfers DROP COLUMN IF EXISTS item;
@@ -0,0 +1,3 @@
+-- purchases are recorded as transfers to the shop, these describe what was bought
+ALTER TABLE transfers ADD COLUMN item VARCHAR(50);
+ALTER TABLE transfers ADD COLUMN quantity INT CHECK (quantity > 0);
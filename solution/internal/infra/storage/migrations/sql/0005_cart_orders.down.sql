@@ -0,0 +1,4 @@
+ALTER TABLE transfers DROP COLUMN IF EXISTS order_id;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS cart_items;
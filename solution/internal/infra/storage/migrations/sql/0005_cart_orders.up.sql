@@ -0,0 +1,25 @@
+CREATE TABLE cart_items (
+	employee_name VARCHAR(50) NOT NULL REFERENCES employees(name),
+	item VARCHAR(50) NOT NULL,
+	quantity INT NOT NULL CHECK (quantity > 0),
+	added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (employee_name, item)
+);
+
+CREATE TABLE orders (
+	id SERIAL PRIMARY KEY,
+	employee_name VARCHAR(50) NOT NULL REFERENCES employees(name),
+	total INT NOT NULL CHECK (total > 0),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE order_items (
+	order_id INT NOT NULL REFERENCES orders(id),
+	item VARCHAR(50) NOT NULL,
+	quantity INT NOT NULL CHECK (quantity > 0),
+	unit_price INT NOT NULL CHECK (unit_price > 0),
+	PRIMARY KEY (order_id, item)
+);
+
+-- the ledger row of a checkout points at the order it paid for
+ALTER TABLE transfers ADD COLUMN order_id INT REFERENCES orders(id);
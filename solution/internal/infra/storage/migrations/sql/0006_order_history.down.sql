@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_employee_name_idx;
+ALTER TABLE orders DROP COLUMN IF EXISTS status;
@@ -0,0 +1,20 @@
+ALTER TABLE orders ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'completed';
+CREATE INDEX orders_employee_name_idx ON orders (employee_name, id DESC);
+
+-- purchases made before orders existed only left a transfer to the shop,
+-- turn the ones that recorded their item into single line orders
+ALTER TABLE orders ADD COLUMN transfer_id INT;
+
+INSERT INTO orders (employee_name, total, transfer_id)
+SELECT sender_name, amount, id
+FROM transfers
+WHERE receiver_name = '' AND order_id IS NULL AND item IS NOT NULL AND quantity IS NOT NULL;
+
+INSERT INTO order_items (order_id, item, quantity, unit_price)
+SELECT o.id, t.item, t.quantity, t.amount / t.quantity
+FROM orders o JOIN transfers t ON t.id = o.transfer_id;
+
+UPDATE transfers t SET order_id = o.id
+FROM orders o WHERE o.transfer_id = t.id;
+
+ALTER TABLE orders DROP COLUMN transfer_id;
@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS cancelled_by;
+ALTER TABLE orders DROP COLUMN IF EXISTS cancelled_at;
@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN cancelled_at TIMESTAMPTZ;
+ALTER TABLE orders ADD COLUMN cancelled_by VARCHAR(50);
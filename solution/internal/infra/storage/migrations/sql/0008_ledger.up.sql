@@ -0,0 +1,46 @@
+CREATE TABLE ledger_transactions (
+	id BIGSERIAL PRIMARY KEY,
+	kind VARCHAR(32) NOT NULL,
+	order_id INT REFERENCES orders(id),
+	transfer_id INT REFERENCES transfers(id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- every transaction moves coins between accounts, so its entries sum to zero
+CREATE TABLE ledger_entries (
+	id BIGSERIAL PRIMARY KEY,
+	transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+	account VARCHAR(64) NOT NULL,
+	amount INT NOT NULL CHECK (amount <> 0)
+);
+CREATE INDEX ledger_entries_transaction_id_idx ON ledger_entries (transaction_id);
+CREATE INDEX ledger_entries_account_idx ON ledger_entries (account);
+
+-- balances existing before the ledger are opened as a single issuance
+WITH opening AS (
+	INSERT INTO ledger_transactions (kind) VALUES ('opening') RETURNING id
+)
+INSERT INTO ledger_entries (transaction_id, account, amount)
+SELECT opening.id, 'employee:' || e.name, e.coins
+FROM opening, employees e
+WHERE e.name <> '' AND e.coins > 0
+UNION ALL
+SELECT opening.id, 'issuance', -SUM(e.coins)
+FROM opening, employees e
+WHERE e.name <> '' AND e.coins > 0
+GROUP BY opening.id;
+
+CREATE FUNCTION ledger_check_balanced() RETURNS trigger AS $$
+BEGIN
+	IF (SELECT SUM(amount) FROM ledger_entries WHERE transaction_id = NEW.transaction_id) <> 0 THEN
+		RAISE EXCEPTION 'ledger transaction % is not balanced', NEW.transaction_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- checked at commit, when all entries of the transaction are in place
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+AFTER INSERT ON ledger_entries
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
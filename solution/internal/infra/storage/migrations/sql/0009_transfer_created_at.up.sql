@@ -0,0 +1,6 @@
+ALTER TABLE transfers ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- transfers made since the ledger was introduced know when they happened
+UPDATE transfers t SET created_at = l.created_at
+FROM ledger_transactions l
+WHERE l.transfer_id = t.id;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS transfers_receiver_name_idx;
+DROP INDEX IF EXISTS transfers_sender_name_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX transfers_sender_name_idx ON transfers (sender_name, created_at DESC, id DESC);
+CREATE INDEX transfers_receiver_name_idx ON transfers (receiver_name, created_at DESC, id DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE transfers DROP COLUMN IF EXISTS tag;
+ALTER TABLE transfers DROP COLUMN IF EXISTS message;
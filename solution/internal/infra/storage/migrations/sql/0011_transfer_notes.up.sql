@@ -0,0 +1,2 @@
+ALTER TABLE transfers ADD COLUMN message VARCHAR(280) NOT NULL DEFAULT '';
+ALTER TABLE transfers ADD COLUMN tag VARCHAR(32) NOT NULL DEFAULT '';
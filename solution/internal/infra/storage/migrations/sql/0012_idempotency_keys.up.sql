@@ -0,0 +1,14 @@
+-- responses of requests sent with an Idempotency-Key, a row without status_code is still in flight
+CREATE TABLE idempotency_keys (
+	employee_name VARCHAR(50) NOT NULL REFERENCES employees(name),
+	key VARCHAR(255) NOT NULL,
+	request_hash CHAR(64) NOT NULL,
+	status_code INT,
+	content_type TEXT NOT NULL DEFAULT '',
+	body BYTEA,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (employee_name, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS invite_codes;
+
+ALTER TABLE employees DROP COLUMN IF EXISTS created_at;
+ALTER TABLE employees DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE employees DROP COLUMN IF EXISTS status;
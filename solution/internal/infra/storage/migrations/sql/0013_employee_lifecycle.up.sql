@@ -0,0 +1,14 @@
+-- deleted employees are kept, their transfers, orders and ledger entries still reference them
+ALTER TABLE employees ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active'
+	CHECK (status IN ('active', 'deactivated', 'deleted'));
+ALTER TABLE employees ADD COLUMN status_changed_at TIMESTAMPTZ;
+ALTER TABLE employees ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE TABLE invite_codes (
+	code VARCHAR(64) PRIMARY KEY,
+	created_by VARCHAR(50) NOT NULL REFERENCES employees(name),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ,
+	used_by VARCHAR(50) REFERENCES employees(name),
+	used_at TIMESTAMPTZ
+);
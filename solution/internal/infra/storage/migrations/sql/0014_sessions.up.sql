@@ -0,0 +1,21 @@
+-- refresh tokens are stored hashed, every rotation adds a row to the same family
+CREATE TABLE refresh_tokens (
+	id SERIAL PRIMARY KEY,
+	family_id VARCHAR(64) NOT NULL,
+	employee_name VARCHAR(50) NOT NULL REFERENCES employees(name),
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	access_jti VARCHAR(64) NOT NULL,
+	access_expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- access tokens revoked before they expire, rows are useless once expires_at has passed
+CREATE TABLE revoked_tokens (
+	jti VARCHAR(64) PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
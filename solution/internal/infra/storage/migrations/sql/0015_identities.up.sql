@@ -0,0 +1,20 @@
+-- links an account at an external identity provider to an employee
+CREATE TABLE employee_identities (
+	provider VARCHAR(50) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	employee_name VARCHAR(50) NOT NULL REFERENCES employees(name),
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_login_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (provider, subject),
+	UNIQUE (provider, employee_name)
+);
+
+-- in-flight authorization code logins, a state is consumed by the callback
+CREATE TABLE oidc_states (
+	state VARCHAR(64) PRIMARY KEY,
+	provider VARCHAR(50) NOT NULL,
+	nonce VARCHAR(64) NOT NULL,
+	code_verifier VARCHAR(128) NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE employee_roles (
+	employee_name VARCHAR(50) NOT NULL REFERENCES employees(name),
+	role VARCHAR(32) NOT NULL,
+	granted_by VARCHAR(50) NOT NULL DEFAULT '',
+	granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (employee_name, role)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE ledger_transactions DROP COLUMN IF EXISTS actor;
+ALTER TABLE ledger_transactions DROP COLUMN IF EXISTS reason;
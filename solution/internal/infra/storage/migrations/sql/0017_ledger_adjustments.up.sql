@@ -0,0 +1,3 @@
+-- manual grants and clawbacks say why they happened and which admin made them
+ALTER TABLE ledger_transactions ADD COLUMN reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE ledger_transactions ADD COLUMN actor VARCHAR(50) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS welcome_grants;
+ALTER TABLE invite_codes DROP COLUMN IF EXISTS department;
+ALTER TABLE employees DROP COLUMN IF EXISTS registration_channel;
+ALTER TABLE employees DROP COLUMN IF EXISTS department;
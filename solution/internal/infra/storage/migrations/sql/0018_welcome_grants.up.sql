@@ -0,0 +1,16 @@
+-- the department and registration channel decide the welcome grant, see WelcomePolicy
+ALTER TABLE employees ADD COLUMN department VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE employees ADD COLUMN registration_channel VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE invite_codes ADD COLUMN department VARCHAR(64) NOT NULL DEFAULT '';
+
+-- the part of a welcome grant that is held back until vest_at
+CREATE TABLE welcome_grants (
+	id BIGSERIAL PRIMARY KEY,
+	employee VARCHAR(50) NOT NULL REFERENCES employees(name),
+	amount INTEGER NOT NULL CHECK (amount > 0),
+	vest_at TIMESTAMPTZ NOT NULL,
+	vested_at TIMESTAMPTZ,
+	cancelled_at TIMESTAMPTZ -- the employee was deleted before the grant vested
+);
+
+CREATE INDEX welcome_grants_pending_idx ON welcome_grants (vest_at) WHERE vested_at IS NULL AND cancelled_at IS NULL;
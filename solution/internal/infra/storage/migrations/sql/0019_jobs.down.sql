@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS allowances;
+DROP TABLE IF EXISTS job_runs;
@@ -0,0 +1,22 @@
+-- one row per job and slot of its schedule, the latest one tells where the job left off
+CREATE TABLE job_runs (
+	id BIGSERIAL PRIMARY KEY,
+	job VARCHAR(64) NOT NULL,
+	scheduled_at TIMESTAMPTZ NOT NULL,
+	status VARCHAR(16) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+	attempts INTEGER NOT NULL DEFAULT 1,
+	error TEXT NOT NULL DEFAULT '',
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ,
+	UNIQUE (job, scheduled_at)
+);
+
+-- an employee gets at most one allowance per period, which makes crediting them idempotent
+CREATE TABLE allowances (
+	employee VARCHAR(50) NOT NULL REFERENCES employees(name),
+	period DATE NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount > 0),
+	transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (employee, period)
+);
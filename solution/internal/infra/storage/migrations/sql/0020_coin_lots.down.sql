@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS coin_lot_spends;
+DROP TABLE IF EXISTS coin_lots;
@@ -0,0 +1,26 @@
+-- coins are tracked in lots by the time they were granted, so old coins can expire.
+-- A lot that moves to another employee keeps its granted_at
+CREATE TABLE coin_lots (
+	id BIGSERIAL PRIMARY KEY,
+	employee VARCHAR(50) NOT NULL REFERENCES employees(name),
+	granted_at TIMESTAMPTZ NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount > 0),
+	remaining INTEGER NOT NULL CHECK (remaining >= 0 AND remaining <= amount),
+	transaction_id BIGINT REFERENCES ledger_transactions(id) -- NULL for balances from before lots
+);
+
+CREATE INDEX coin_lots_open_idx ON coin_lots (employee, granted_at, id) WHERE remaining > 0;
+CREATE INDEX coin_lots_granted_at_idx ON coin_lots (granted_at) WHERE remaining > 0;
+
+-- which lots a ledger transaction took coins from, a refund gives back the lots of the purchase
+CREATE TABLE coin_lot_spends (
+	lot_id BIGINT NOT NULL REFERENCES coin_lots(id),
+	transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+	amount INTEGER NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX coin_lot_spends_transaction_idx ON coin_lot_spends (transaction_id);
+
+-- existing balances become a lot each, granted now so nobody's coins expire the day this ships
+INSERT INTO coin_lots (employee, granted_at, amount, remaining)
+SELECT name, now(), coins, coins FROM employees WHERE name <> '' AND coins > 0;
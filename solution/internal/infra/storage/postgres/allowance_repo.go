@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/ledger"
+)
+
+type AllowanceRepository struct {
+	db *sql.DB
+}
+
+func NewAllowanceRepository(db *sql.DB) *AllowanceRepository {
+	return &AllowanceRepository{db: db}
+}
+
+// Credit credits one employee per transaction, so a failure keeps what was already credited
+// and a rerun picks up from there. The shop sentinel is an account, not an employee
+func (r *AllowanceRepository) Credit(ctx context.Context, period time.Time, amount int) (int64, error) {
+	const op = "infra.storage.postgres.CreditAllowance"
+
+	reason := "monthly allowance " + period.Format("2006-01")
+	var credited int64
+	for {
+		done := false
+		err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+			var name string
+			err := tx.QueryRowContext(ctx, `
+			SELECT e.name FROM employees e
+			WHERE e.status='active' AND e.name <> '' AND NOT EXISTS (
+				SELECT 1 FROM allowances a WHERE a.employee=e.name AND a.period=$1
+			)
+			ORDER BY e.name
+			LIMIT 1
+			FOR UPDATE OF e;`, period,
+			).Scan(&name)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					done = true
+					return nil
+				}
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `UPDATE employees SET coins=coins+$1 WHERE name=$2;`, amount, name)
+			if err != nil {
+				return err
+			}
+
+			t := &ledger.Transaction{Kind: ledger.KindAllowance, Reason: reason}
+			err = postLedger(ctx, tx, t, ledger.Movement{
+				From:   ledger.AccountIssuance,
+				To:     ledger.EmployeeAccount(name),
+				Amount: amount,
+			})
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `INSERT INTO allowances (employee, period, amount, transaction_id) VALUES ($1, $2, $3, $4);`,
+				name, period, amount, t.ID,
+			)
+			return err
+		})
+		if err != nil {
+			return credited, fmt.Errorf("%s: %w", op, err)
+		}
+		if done {
+			return credited, nil
+		}
+		credited++
+	}
+}
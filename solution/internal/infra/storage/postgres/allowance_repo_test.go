@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/employee"
+)
+
+func TestCreditSkipsShopSentinel(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	// a period no earlier run has credited, so every active employee is due
+	period := time.Date(2200, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(time.Now().UnixNano()/1000%3_000_000))
+
+	before := testCoins(t, db, employee.ShopAccount)
+	colleague := newTestEmployee(t, db, 0)
+	if _, err := NewAllowanceRepository(db).Credit(ctx, period, 100); err != nil {
+		t.Fatalf("credit: %v", err)
+	}
+
+	if got := testCoins(t, db, employee.ShopAccount); got != before {
+		t.Errorf("shop sentinel has %d coins, want %d", got, before)
+	}
+	if got := testCoins(t, db, colleague); got != 100 {
+		t.Errorf("employee has %d coins, want the allowance of 100", got)
+	}
+}
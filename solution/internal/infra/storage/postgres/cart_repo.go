@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/wdsjk/avito-shop/internal/cart"
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+var (
+	ErrCartItemNotFound = errors.New("item is not in the cart")
+	ErrCartChanged      = errors.New("cart changed during checkout")
+)
+
+type CartRepository struct {
+	db *sql.DB
+}
+
+func NewCartRepository(db *sql.DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+func (r *CartRepository) AddItem(ctx context.Context, name, item string, quantity int) (*cart.Item, error) {
+	const op = "infra.storage.postgres.AddCartItem"
+
+	// the WHERE guard keeps the accumulated quantity within a single purchase limit
+	var saved cart.Item
+	err := r.db.QueryRowContext(ctx, `
+	INSERT INTO cart_items (employee_name, item, quantity) VALUES ($1, $2, $3)
+	ON CONFLICT (employee_name, item) DO UPDATE SET quantity=cart_items.quantity+EXCLUDED.quantity
+	WHERE cart_items.quantity+EXCLUDED.quantity <= $4
+	RETURNING employee_name, item, quantity, added_at;`,
+		name, item, quantity, shop.MaxQuantity,
+	).Scan(&saved.EmployeeName, &saved.Name, &saved.Quantity, &saved.AddedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", op, shop.ErrBadQuantity)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &saved, nil
+}
+
+func (r *CartRepository) RemoveItem(ctx context.Context, name, item string, quantity int) error {
+	const op = "infra.storage.postgres.RemoveCartItem"
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var current int
+		err := tx.QueryRowContext(ctx, `SELECT quantity FROM cart_items WHERE employee_name=$1 AND item=$2 FOR UPDATE;`, name, item).Scan(&current)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrCartItemNotFound
+			}
+			return err
+		}
+
+		if quantity == 0 || quantity >= current {
+			_, err = tx.ExecContext(ctx, `DELETE FROM cart_items WHERE employee_name=$1 AND item=$2;`, name, item)
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE cart_items SET quantity=$1 WHERE employee_name=$2 AND item=$3;`, current-quantity, name, item)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *CartRepository) GetItems(ctx context.Context, name string) ([]*cart.Item, error) {
+	const op = "infra.storage.postgres.GetCartItems"
+
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT employee_name, item, quantity, added_at
+	FROM cart_items WHERE employee_name=$1
+	ORDER BY added_at, item;`, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var items []*cart.Item
+	for rows.Next() {
+		var item cart.Item
+		if err := rows.Scan(&item.EmployeeName, &item.Name, &item.Quantity, &item.AddedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
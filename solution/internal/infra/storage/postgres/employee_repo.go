@@ -5,16 +5,25 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/ledger"
+	"github.com/wdsjk/avito-shop/internal/order"
 	"github.com/wdsjk/avito-shop/internal/shop"
 	"github.com/wdsjk/avito-shop/internal/transfer"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// welcomeReason is recorded on the ledger transactions of welcome grants
+const welcomeReason = "welcome grant"
+
 var (
 	ErrEmpNotFound  = errors.New("employee not found")
+	ErrEmpExists    = errors.New("employee already exists")
 	ErrItemNotFound = errors.New("item not found")
 	ErrNoCoins      = errors.New("not enough coins")
 )
@@ -27,37 +36,78 @@ func NewEmployeeRepository(db *sql.DB) *EmployeeRepository {
 	return &EmployeeRepository{db: db}
 }
 
-func (r *EmployeeRepository) SaveEmployee(ctx context.Context, name string, password string) (string, error) {
+func (r *EmployeeRepository) SaveEmployee(ctx context.Context, reg *employee.Registration) (string, error) {
 	const op = "infra.storage.postgres.SaveEmployee"
 
-	stmt, err := r.db.PrepareContext(ctx, `INSERT INTO employees (name, password, coins, bought_items) VALUES ($1, $2, $3, $4);`)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(reg.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
-	}
+	err = withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO employees (name, password, coins, bought_items, department, registration_channel)
+		VALUES ($1, $2, $3, $4, $5, $6);`,
+			reg.Name, hashedPassword, reg.Grant.Amount, employee.Inventory{}, reg.Department, reg.Channel,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrEmpExists
+			}
+			return err
+		}
+
+		if reg.Invite != "" {
+			res, err := tx.ExecContext(ctx, `
+			UPDATE invite_codes SET used_by=$2, used_at=now()
+			WHERE code=$1 AND used_by IS NULL AND (expires_at IS NULL OR expires_at > now());`,
+				reg.Invite, reg.Name,
+			)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 {
+				return employee.ErrInvalidInvite
+			}
+		}
+
+		if reg.Grant.Vesting > 0 {
+			_, err := tx.ExecContext(ctx, `INSERT INTO welcome_grants (employee, amount, vest_at) VALUES ($1, $2, $3);`,
+				reg.Name, reg.Grant.Vesting, reg.Grant.VestAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
 
-	_, err = stmt.ExecContext(ctx, name, hashedPassword, 1000, employee.Inventory{})
+		if reg.Grant.Amount == 0 {
+			return nil
+		}
+		return postLedger(ctx, tx, &ledger.Transaction{Kind: ledger.KindIssuance, Reason: welcomeReason}, ledger.Movement{
+			From:   ledger.AccountIssuance,
+			To:     ledger.EmployeeAccount(reg.Name),
+			Amount: reg.Grant.Amount,
+		})
+	})
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return name, nil
+	return reg.Name, nil
 }
 
 func (r *EmployeeRepository) GetEmployee(ctx context.Context, name string) (*employee.Employee, error) {
 	const op = "infra.storage.postgres.GetEmployeeInfo"
 
-	stmt, err := r.db.PrepareContext(ctx, `SELECT * FROM employees WHERE name=$1;`)
+	stmt, err := r.db.PrepareContext(ctx, `SELECT id, name, password, coins, bought_items, status, department, created_at FROM employees WHERE name=$1;`)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	var emp employee.Employee
-	err = stmt.QueryRowContext(ctx, name).Scan(&emp.ID, &emp.Name, &emp.Password, &emp.Coins, &emp.Inventory)
+	err = stmt.QueryRowContext(ctx, name).Scan(&emp.ID, &emp.Name, &emp.Password, &emp.Coins, &emp.Inventory, &emp.Status, &emp.Department, &emp.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("%s: %w", op, ErrEmpNotFound)
@@ -68,39 +118,203 @@ func (r *EmployeeRepository) GetEmployee(ctx context.Context, name string) (*emp
 	return &emp, nil
 }
 
-func (r *EmployeeRepository) BuyItem(ctx context.Context, name, item string, shop shop.Shop, t *transfer.TransferService) error {
-	const op = "infra.storage.postgres.BuyItem"
+func (r *EmployeeRepository) GetStatus(ctx context.Context, name string) (string, error) {
+	const op = "infra.storage.postgres.GetEmployeeStatus"
 
-	emp, err := r.GetEmployee(ctx, name)
+	var status string
+	err := r.db.QueryRowContext(ctx, `SELECT status FROM employees WHERE name=$1;`, name).Scan(&status)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, ErrEmpNotFound)
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	price, ok := shop[item]
-	if !ok {
-		return fmt.Errorf("%s: %w", op, ErrItemNotFound)
+	return status, nil
+}
+
+func (r *EmployeeRepository) SetStatus(ctx context.Context, name, status string, from ...string) (*employee.Employee, error) {
+	const op = "infra.storage.postgres.SetEmployeeStatus"
+
+	var emp *employee.Employee
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		emp, err = lockEmployee(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(from, emp.Status) {
+			return employee.ErrStatusTransition
+		}
+		if emp.Status == status {
+			return nil
+		}
+
+		// a deleted account keeps its row for history, but can never log in again
+		err = tx.QueryRowContext(ctx, `
+		UPDATE employees SET status=$2, status_changed_at=now(),
+			password=CASE WHEN $2='deleted' THEN '' ELSE password END
+		WHERE name=$1
+		RETURNING status;`,
+			name, status,
+		).Scan(&emp.Status)
+		if err != nil {
+			return err
+		}
+
+		if status == employee.StatusDeleted {
+			_, err = tx.ExecContext(ctx, `DELETE FROM cart_items WHERE employee_name=$1;`, name)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if emp.Coins-price < 0 {
-		return fmt.Errorf("%s: %w", op, ErrNoCoins)
+	return emp, nil
+}
+
+func (r *EmployeeRepository) SaveInvite(ctx context.Context, invite *employee.Invite) error {
+	const op = "infra.storage.postgres.SaveInvite"
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO invite_codes (code, department, created_by, created_at, expires_at) VALUES ($1, $2, $3, $4, $5);`,
+		invite.Code, invite.Department, invite.CreatedBy, invite.CreatedAt, invite.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if emp.Inventory == nil {
-		emp.Inventory = make(employee.Inventory)
+	return nil
+}
+
+func (r *EmployeeRepository) GetInvite(ctx context.Context, code string) (*employee.Invite, error) {
+	const op = "infra.storage.postgres.GetInvite"
+
+	var invite employee.Invite
+	err := r.db.QueryRowContext(ctx, `
+	SELECT code, department, created_by, created_at, expires_at FROM invite_codes
+	WHERE code=$1 AND used_by IS NULL AND (expires_at IS NULL OR expires_at > now());`, code,
+	).Scan(&invite.Code, &invite.Department, &invite.CreatedBy, &invite.CreatedAt, &invite.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", op, employee.ErrInvalidInvite)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &invite, nil
+}
+
+// VestWelcomeGrants credits each due grant in its own transaction, so one failing grant
+// doesn't hold back the rest. Grants of deleted employees are cancelled instead
+func (r *EmployeeRepository) VestWelcomeGrants(ctx context.Context, now time.Time) (int64, error) {
+	const op = "infra.storage.postgres.VestWelcomeGrants"
+
+	var vested int64
+	for {
+		done, credited := false, false
+		err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+			var id int64
+			var name string
+			var amount int
+			err := tx.QueryRowContext(ctx, `
+			SELECT id, employee, amount FROM welcome_grants
+			WHERE vested_at IS NULL AND cancelled_at IS NULL AND vest_at <= $1
+			ORDER BY vest_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED;`, now,
+			).Scan(&id, &name, &amount)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					done = true
+					return nil
+				}
+				return err
+			}
+
+			emp, err := lockEmployee(ctx, tx, name)
+			if err != nil {
+				return err
+			}
+			if emp.Status == employee.StatusDeleted {
+				_, err := tx.ExecContext(ctx, `UPDATE welcome_grants SET cancelled_at=now() WHERE id=$1;`, id)
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `UPDATE employees SET coins=coins+$1 WHERE name=$2;`, amount, name)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `UPDATE welcome_grants SET vested_at=now() WHERE id=$1;`, id)
+			if err != nil {
+				return err
+			}
+
+			credited = true
+			return postLedger(ctx, tx, &ledger.Transaction{Kind: ledger.KindIssuance, Reason: welcomeReason + ", vested"}, ledger.Movement{
+				From:   ledger.AccountIssuance,
+				To:     ledger.EmployeeAccount(name),
+				Amount: amount,
+			})
+		})
+		if err != nil {
+			return vested, fmt.Errorf("%s: %w", op, err)
+		}
+		if done {
+			return vested, nil
+		}
+		if credited {
+			vested++
+		}
 	}
-	emp.Inventory[item] = emp.Inventory[item] + 1
+}
+
+func (r *EmployeeRepository) GetRoles(ctx context.Context, name string) ([]string, error) {
+	const op = "infra.storage.postgres.GetEmployeeRoles"
 
-	stmt, err := r.db.PrepareContext(ctx, `UPDATE employees SET coins=$1, bought_items=$2 WHERE name=$3;`)
+	rows, err := r.db.QueryContext(ctx, `SELECT role FROM employee_roles WHERE employee_name=$1 ORDER BY role;`, name)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	roles := make([]string, 0)
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.ExecContext(ctx, emp.Coins-price, emp.Inventory, name)
+	return roles, nil
+}
+
+func (r *EmployeeRepository) GrantRole(ctx context.Context, name, role, grantedBy string) error {
+	const op = "infra.storage.postgres.GrantRole"
+
+	_, err := r.db.ExecContext(ctx, `
+	INSERT INTO employee_roles (employee_name, role, granted_by) VALUES ($1, $2, $3)
+	ON CONFLICT (employee_name, role) DO NOTHING;`,
+		name, role, grantedBy,
+	)
 	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("%s: %w", op, ErrEmpNotFound)
+		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = t.SaveTransfer(ctx, name, "", price)
+	return nil
+}
+
+func (r *EmployeeRepository) RevokeRole(ctx context.Context, name, role string) error {
+	const op = "infra.storage.postgres.RevokeRole"
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM employee_roles WHERE employee_name=$1 AND role=$2;`, name, role)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -108,46 +322,198 @@ func (r *EmployeeRepository) BuyItem(ctx context.Context, name, item string, sho
 	return nil
 }
 
-func (r *EmployeeRepository) TransferCoins(ctx context.Context, senderName, receiverName string, amount int, t *transfer.TransferService) error {
-	const op = "infra.storage.postgres.TransferCoins"
+func (r *EmployeeRepository) BuyItem(ctx context.Context, name string, item *shop.Item, quantity int) (*order.Order, error) {
+	const op = "infra.storage.postgres.BuyItem"
 
-	sender, err := r.GetEmployee(ctx, senderName)
+	var ord *order.Order
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		ord, err = placeOrder(ctx, tx, name, []*order.Line{
+			{Item: item.Name, Quantity: quantity, UnitPrice: item.Price},
+		})
+		return err
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("%s: %w", op, ErrEmpNotFound)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ord, nil
+}
+
+func (r *EmployeeRepository) TransferCoins(ctx context.Context, senderName, receiverName string, amount int, note transfer.Note, rules transfer.Rules) error {
+	const op = "infra.storage.postgres.TransferCoins"
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		// rows are always locked in name order, so two opposite transfers
+		// between the same pair of employees can't deadlock each other
+		first, second := senderName, receiverName
+		if second < first {
+			first, second = second, first
+		}
+
+		locked := make(map[string]*employee.Employee, 2)
+		for _, name := range []string{first, second} {
+			emp, err := lockEmployee(ctx, tx, name)
+			if err != nil {
+				return err
+			}
+			locked[name] = emp
+		}
+
+		// deactivated and deleted employees can't be paid
+		if !locked[receiverName].Active() {
+			return ErrEmpNotFound
+		}
+
+		// the sender is locked, so their concurrent transfers are checked one after another
+		now := time.Now()
+		stats, err := transferStats(ctx, tx, senderName, receiverName, rules.StartOfDay(now))
+		if err != nil {
+			return err
+		}
+		if err := rules.Check(amount, stats, now); err != nil {
+			return err
+		}
+
+		if locked[senderName].Coins-amount < 0 {
+			return ErrNoCoins
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE employees SET coins=coins-$1 WHERE name=$2;`, amount, senderName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE employees SET coins=coins+$1 WHERE name=$2;`, amount, receiverName)
+		if err != nil {
+			return err
+		}
+
+		transferID, err := saveTransfer(ctx, tx, senderName, receiverName, amount, note)
+		if err != nil {
+			return err
 		}
+
+		return postLedger(ctx, tx, &ledger.Transaction{Kind: ledger.KindTransfer, TransferID: &transferID}, ledger.Movement{
+			From:   ledger.EmployeeAccount(senderName),
+			To:     ledger.EmployeeAccount(receiverName),
+			Amount: amount,
+		})
+	})
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	receiver, err := r.GetEmployee(ctx, receiverName)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("%s: %w", op, ErrEmpNotFound)
+
+	return nil
+}
+
+func (r *EmployeeRepository) Checkout(ctx context.Context, name string, lines []*order.Line) (*order.Order, error) {
+	const op = "infra.storage.postgres.Checkout"
+
+	var ord *order.Order
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		// only the exact lines that were priced are paid for, anything
+		// changed in the cart meanwhile aborts the checkout
+		for _, line := range lines {
+			res, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE employee_name=$1 AND item=$2 AND quantity=$3;`, name, line.Item, line.Quantity)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return ErrCartChanged
+			}
 		}
-		return fmt.Errorf("%s: %w", op, err)
+
+		var err error
+		ord, err = placeOrder(ctx, tx, name, lines)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if sender.Coins-amount < 0 {
-		return fmt.Errorf("%s: %w", op, ErrNoCoins)
+	return ord, nil
+}
+
+// placeOrder debits the employee, takes stock, fills the inventory and records
+// the order with its ledger transaction, all within tx
+func placeOrder(ctx context.Context, tx *sql.Tx, name string, lines []*order.Line) (*order.Order, error) {
+	// stock rows are locked in item order for the same reason employees are in TransferCoins
+	sorted := make([]*order.Line, len(lines))
+	copy(sorted, lines)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Item < sorted[j].Item
+	})
+
+	ord := &order.Order{EmployeeName: name, Items: sorted, Status: order.StatusCompleted}
+	for _, line := range sorted {
+		ord.Total += line.Subtotal()
 	}
 
-	stmt, err := r.db.PrepareContext(ctx, `UPDATE employees SET coins=$1 WHERE name=$2;`)
+	emp, err := lockEmployee(ctx, tx, name)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, err
+	}
+
+	if emp.Coins-ord.Total < 0 {
+		return nil, ErrNoCoins
+	}
+
+	if emp.Inventory == nil {
+		emp.Inventory = make(employee.Inventory)
+	}
+	for _, line := range sorted {
+		if err := takeStock(ctx, tx, line.Item, line.Quantity); err != nil {
+			return nil, err
+		}
+		emp.Inventory[line.Item] = emp.Inventory[line.Item] + line.Quantity
 	}
 
-	_, err = stmt.ExecContext(ctx, sender.Coins-amount, senderName)
+	_, err = tx.ExecContext(ctx, `UPDATE employees SET coins=$1, bought_items=$2 WHERE name=$3;`, emp.Coins-ord.Total, emp.Inventory, name)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
-	_, err = stmt.ExecContext(ctx, receiver.Coins+amount, receiverName)
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO orders (employee_name, total, status) VALUES ($1, $2, $3) RETURNING id, created_at;`,
+		name, ord.Total, ord.Status,
+	).Scan(&ord.ID, &ord.CreatedAt)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, err
+	}
+	for _, line := range sorted {
+		_, err := tx.ExecContext(ctx, `INSERT INTO order_items (order_id, item, quantity, unit_price) VALUES ($1, $2, $3, $4);`,
+			ord.ID, line.Item, line.Quantity, line.UnitPrice)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	err = t.SaveTransfer(ctx, senderName, receiverName, amount)
+	err = postLedger(ctx, tx, &ledger.Transaction{Kind: ledger.KindPurchase, OrderID: &ord.ID}, ledger.Movement{
+		From:   ledger.EmployeeAccount(name),
+		To:     ledger.AccountShop,
+		Amount: ord.Total,
+	})
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
 
-	return nil
+	return ord, nil
+}
+
+// lockEmployee reads the employee row with FOR UPDATE, holding the lock until tx ends
+func lockEmployee(ctx context.Context, tx *sql.Tx, name string) (*employee.Employee, error) {
+	var emp employee.Employee
+	err := tx.QueryRowContext(ctx, `SELECT id, name, password, coins, bought_items, status, department, created_at FROM employees WHERE name=$1 FOR UPDATE;`, name).
+		Scan(&emp.ID, &emp.Name, &emp.Password, &emp.Coins, &emp.Inventory, &emp.Status, &emp.Department, &emp.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrEmpNotFound
+		}
+		return nil, err
+	}
+
+	return &emp, nil
 }
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wdsjk/avito-shop/internal/transfer"
+)
+
+func TestConcurrentDebitsOfOneSender(t *testing.T) {
+	db := testDB(t)
+	repo := NewEmployeeRepository(db)
+	ctx := context.Background()
+
+	const (
+		initial = 100
+		amount  = 10
+		workers = 20 // transfers and purchases each, together worth four times the balance
+	)
+	sender := newTestEmployee(t, db, initial)
+	receiver := newTestEmployee(t, db, 0)
+	item := newTestItem(t, db, amount, nil)
+
+	var transfers, purchases atomic.Int64
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			err := repo.TransferCoins(ctx, sender, receiver, amount, transfer.Note{}, transfer.Rules{})
+			if err == nil {
+				transfers.Add(1)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.BuyItem(ctx, sender, item, 1)
+			if err == nil {
+				purchases.Add(1)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil && !errors.Is(err, ErrNoCoins) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	debited := int(transfers.Load()+purchases.Load()) * amount
+	if debited != initial {
+		t.Errorf("debited %d coins, want the whole balance of %d", debited, initial)
+	}
+	if got := testCoins(t, db, sender); got != initial-debited || got < 0 {
+		t.Errorf("sender has %d coins, want %d", got, initial-debited)
+	}
+	if got, want := testCoins(t, db, receiver), int(transfers.Load())*amount; got != want {
+		t.Errorf("receiver has %d coins, want %d", got, want)
+	}
+}
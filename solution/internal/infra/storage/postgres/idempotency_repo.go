@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/idempotency"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, name, key, requestHash string, expiresAt time.Time) (*idempotency.Record, error) {
+	const op = "infra.storage.postgres.ReserveIdempotencyKey"
+
+	// the key may be released by a concurrent request between the two statements, one more round settles it
+	for range 2 {
+		// an expired record is taken over as if the key was never used
+		res, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (employee_name, key, request_hash, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (employee_name, key) DO UPDATE SET
+			request_hash=EXCLUDED.request_hash, status_code=NULL, content_type='', body=NULL,
+			created_at=now(), expires_at=EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now();`,
+			name, key, requestHash, expiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		} else if n == 1 {
+			return nil, nil
+		}
+
+		var rec idempotency.Record
+		var statusCode sql.NullInt32
+		err = r.db.QueryRowContext(ctx, `
+		SELECT employee_name, key, request_hash, status_code, content_type, body, created_at, expires_at
+		FROM idempotency_keys WHERE employee_name=$1 AND key=$2;`,
+			name, key,
+		).Scan(&rec.EmployeeName, &rec.Key, &rec.RequestHash, &statusCode, &rec.ContentType, &rec.Body, &rec.CreatedAt, &rec.ExpiresAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		rec.StatusCode = int(statusCode.Int32)
+
+		return &rec, nil
+	}
+
+	return nil, fmt.Errorf("%s: %w", op, idempotency.ErrInProgress)
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, name, key string, statusCode int, contentType string, body []byte) error {
+	const op = "infra.storage.postgres.CompleteIdempotencyKey"
+
+	_, err := r.db.ExecContext(ctx, `
+	UPDATE idempotency_keys SET status_code=$3, content_type=$4, body=$5
+	WHERE employee_name=$1 AND key=$2;`,
+		name, key, statusCode, contentType, body,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, name, key string) error {
+	const op = "infra.storage.postgres.ReleaseIdempotencyKey"
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE employee_name=$1 AND key=$2 AND status_code IS NULL;`, name, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	const op = "infra.storage.postgres.DeleteExpiredIdempotencyKeys"
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now();`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wdsjk/avito-shop/internal/identity"
+)
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) SaveState(ctx context.Context, state *identity.LoginState) error {
+	const op = "infra.storage.postgres.SaveLoginState"
+
+	// abandoned logins are cleaned up here instead of by a separate job
+	_, err := r.db.ExecContext(ctx, `DELETE FROM oidc_states WHERE expires_at <= now();`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO oidc_states (state, provider, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4, $5);`,
+		state.State, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *IdentityRepository) TakeState(ctx context.Context, state string) (*identity.LoginState, error) {
+	const op = "infra.storage.postgres.TakeLoginState"
+
+	var login identity.LoginState
+	err := r.db.QueryRowContext(ctx, `
+	DELETE FROM oidc_states WHERE state=$1 AND expires_at > now()
+	RETURNING state, provider, nonce, code_verifier, expires_at;`, state,
+	).Scan(&login.State, &login.Provider, &login.Nonce, &login.CodeVerifier, &login.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &login, nil
+}
+
+func (r *IdentityRepository) FindEmployee(ctx context.Context, provider, subject string) (string, error) {
+	const op = "infra.storage.postgres.FindIdentityEmployee"
+
+	var name string
+	err := r.db.QueryRowContext(ctx, `
+	UPDATE employee_identities SET last_login_at=now()
+	WHERE provider=$1 AND subject=$2
+	RETURNING employee_name;`, provider, subject,
+	).Scan(&name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return name, nil
+}
+
+func (r *IdentityRepository) Link(ctx context.Context, ident *identity.Identity, name string) error {
+	const op = "infra.storage.postgres.LinkIdentity"
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO employee_identities (provider, subject, employee_name, email) VALUES ($1, $2, $3, $4);`,
+		ident.Provider, ident.Subject, name, ident.Email,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, identity.ErrIdentityConflict)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
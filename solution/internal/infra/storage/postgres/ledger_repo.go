@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/ledger"
+)
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+func (r *LedgerRepository) Reconcile(ctx context.Context) (*ledger.Reconciliation, error) {
+	const op = "infra.storage.postgres.Reconcile"
+
+	// a repeatable read snapshot keeps the three checks consistent with each other
+	rec := &ledger.Reconciliation{CheckedAt: time.Now()}
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount), 0) FROM ledger_entries;`).Scan(&rec.Total)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT transaction_id FROM ledger_entries
+	GROUP BY transaction_id HAVING SUM(amount) <> 0
+	ORDER BY transaction_id;`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		rec.Unbalanced = append(rec.Unbalanced, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+	SELECT e.name, COALESCE(e.coins, 0), COALESCE(l.balance, 0)
+	FROM employees e
+	LEFT JOIN (
+		SELECT account, SUM(amount) AS balance FROM ledger_entries GROUP BY account
+	) l ON l.account = $1::text || e.name
+	WHERE e.name <> '' AND COALESCE(e.coins, 0) <> COALESCE(l.balance, 0)
+	ORDER BY e.name;`, ledger.EmployeeAccount(""))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m ledger.Mismatch
+		if err := rows.Scan(&m.Employee, &m.Cached, &m.Ledger); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		rec.Mismatches = append(rec.Mismatches, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rec, nil
+}
+
+// errDryRun rolls back a batch that was only applied to see its outcome
+var errDryRun = errors.New("dry run")
+
+func (r *LedgerRepository) Adjust(ctx context.Context, batch *ledger.Batch) (*ledger.BatchResult, error) {
+	const op = "infra.storage.postgres.Adjust"
+
+	// employees are locked in name order, like in TransferCoins
+	sorted := make([]*ledger.Adjustment, len(batch.Adjustments))
+	copy(sorted, batch.Adjustments)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Employee < sorted[j].Employee
+	})
+
+	res := &ledger.BatchResult{Kind: batch.Kind(), DryRun: batch.DryRun}
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		movements := make([]ledger.Movement, 0, len(sorted))
+		for _, a := range sorted {
+			emp, err := lockEmployee(ctx, tx, a.Employee)
+			if err != nil {
+				return &ledger.AdjustmentError{Employee: a.Employee, Err: err}
+			}
+			if emp.Status == employee.StatusDeleted {
+				return &ledger.AdjustmentError{Employee: a.Employee, Err: ErrEmpNotFound}
+			}
+			if emp.Coins+a.Amount < 0 {
+				return &ledger.AdjustmentError{Employee: a.Employee, Err: ErrNoCoins}
+			}
+
+			_, err = tx.ExecContext(ctx, `UPDATE employees SET coins=coins+$1 WHERE name=$2;`, a.Amount, a.Employee)
+			if err != nil {
+				return err
+			}
+
+			m := ledger.Movement{From: ledger.AccountIssuance, To: ledger.EmployeeAccount(a.Employee), Amount: a.Amount}
+			if a.Amount < 0 {
+				m = ledger.Movement{From: ledger.EmployeeAccount(a.Employee), To: ledger.AccountIssuance, Amount: -a.Amount}
+			}
+			movements = append(movements, m)
+
+			res.Adjustments = append(res.Adjustments, &ledger.AdjustmentResult{
+				Employee: a.Employee,
+				Amount:   a.Amount,
+				Before:   emp.Coins,
+				After:    emp.Coins + a.Amount,
+			})
+			res.Total += a.Amount
+		}
+
+		t := &ledger.Transaction{Kind: res.Kind, Reason: batch.Reason, Actor: batch.Actor}
+		if err := postLedger(ctx, tx, t, movements...); err != nil {
+			return err
+		}
+
+		if batch.DryRun {
+			return errDryRun
+		}
+		res.TransactionID = t.ID
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// postLedger records t with a debit and a credit entry per movement and moves the coin lots
+// along, it must run in the same transaction as the balance updates the movements describe
+func postLedger(ctx context.Context, q querier, t *ledger.Transaction, movements ...ledger.Movement) error {
+	err := q.QueryRowContext(ctx, `
+	INSERT INTO ledger_transactions (kind, order_id, transfer_id, reason, actor)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at;`, t.Kind, t.OrderID, t.TransferID, t.Reason, t.Actor,
+	).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range movements {
+		_, err := q.ExecContext(ctx, `
+		INSERT INTO ledger_entries (transaction_id, account, amount)
+		VALUES ($1, $2, $3), ($1, $4, $5);`, t.ID, m.From, -m.Amount, m.To, m.Amount)
+		if err != nil {
+			return err
+		}
+
+		if err := moveLots(ctx, q, t, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
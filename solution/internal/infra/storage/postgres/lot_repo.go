@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/ledger"
+	"github.com/wdsjk/avito-shop/internal/lot"
+)
+
+// errLotsShort means the lots of an employee add up to less than their balance
+var errLotsShort = errors.New("coin lots don't cover the balance")
+
+type LotRepository struct {
+	db *sql.DB
+}
+
+func NewLotRepository(db *sql.DB) *LotRepository {
+	return &LotRepository{db: db}
+}
+
+// Expire expires the coins of one employee per transaction, a rerun after a failure
+// picks up the employees that are left
+func (r *LotRepository) Expire(ctx context.Context, cutoff time.Time) (int64, error) {
+	const op = "infra.storage.postgres.ExpireCoins"
+
+	reason := "coins granted before " + cutoff.Format(time.DateOnly) + " expired"
+	var expired int64
+	for {
+		done := false
+		err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+			var name string
+			err := tx.QueryRowContext(ctx, `
+			SELECT employee FROM coin_lots WHERE remaining > 0 AND granted_at < $1
+			ORDER BY employee LIMIT 1;`, cutoff,
+			).Scan(&name)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					done = true
+					return nil
+				}
+				return err
+			}
+
+			// lots only change together with the balance, under the employee's lock
+			if _, err := lockEmployee(ctx, tx, name); err != nil {
+				return err
+			}
+			var amount int
+			err = tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(remaining), 0) FROM coin_lots WHERE employee=$1 AND remaining > 0 AND granted_at < $2;`,
+				name, cutoff,
+			).Scan(&amount)
+			if err != nil || amount == 0 {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `UPDATE employees SET coins=coins-$1 WHERE name=$2;`, amount, name)
+			if err != nil {
+				return err
+			}
+
+			// the expiring lots are the oldest ones, so taking them first in first out takes exactly them
+			return postLedger(ctx, tx, &ledger.Transaction{Kind: ledger.KindExpiry, Reason: reason}, ledger.Movement{
+				From:   ledger.EmployeeAccount(name),
+				To:     ledger.AccountExpired,
+				Amount: amount,
+			})
+		})
+		if err != nil {
+			return expired, fmt.Errorf("%s: %w", op, err)
+		}
+		if done {
+			return expired, nil
+		}
+		expired++
+	}
+}
+
+func (r *LotRepository) Upcoming(ctx context.Context, name string, until time.Time) ([]*lot.Lot, error) {
+	const op = "infra.storage.postgres.UpcomingExpirations"
+
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT MIN(granted_at), SUM(remaining) FROM coin_lots
+	WHERE employee=$1 AND remaining > 0 AND granted_at < $2
+	GROUP BY date_trunc('day', granted_at)
+	ORDER BY MIN(granted_at);`, name, until,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	lots := make([]*lot.Lot, 0)
+	for rows.Next() {
+		l := &lot.Lot{Employee: name}
+		if err := rows.Scan(&l.GrantedAt, &l.Remaining); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		l.Amount = l.Remaining
+		lots = append(lots, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lots, nil
+}
+
+// lotPortion is coins taken from or given to a lot, they keep the grant time of the lot
+type lotPortion struct {
+	grantedAt time.Time
+	amount    int
+}
+
+// moveLots keeps the lots in step with a movement: coins leaving an employee are taken from
+// their oldest lots first, coins reaching an employee become new lots. It runs under the
+// locks of the employees whose balances the movement changes
+func moveLots(ctx context.Context, q querier, t *ledger.Transaction, m ledger.Movement) error {
+	var portions []lotPortion
+	if name, ok := ledger.EmployeeName(m.From); ok {
+		var err error
+		portions, err = takeLots(ctx, q, name, m.Amount, t.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	name, ok := ledger.EmployeeName(m.To)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case portions != nil:
+		// transferred coins keep their age, passing them around doesn't put off their expiry
+	case t.Kind == ledger.KindRefund && t.OrderID != nil:
+		var err error
+		portions, err = refundedLots(ctx, q, *t.OrderID, m.Amount, t.CreatedAt)
+		if err != nil {
+			return err
+		}
+	default:
+		portions = []lotPortion{{grantedAt: t.CreatedAt, amount: m.Amount}}
+	}
+
+	for _, p := range portions {
+		_, err := q.ExecContext(ctx, `
+		INSERT INTO coin_lots (employee, granted_at, amount, remaining, transaction_id) VALUES ($1, $2, $3, $3, $4);`,
+			name, p.grantedAt, p.amount, t.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// takeLots takes amount coins from the employee's oldest lots and records which lots paid for transactionID
+func takeLots(ctx context.Context, q querier, name string, amount int, transactionID int64) ([]lotPortion, error) {
+	rows, err := q.QueryContext(ctx, `
+	WITH open AS (
+		SELECT id, remaining, SUM(remaining) OVER (ORDER BY granted_at, id) AS running
+		FROM coin_lots WHERE employee=$1 AND remaining > 0
+	), taken AS (
+		UPDATE coin_lots l SET remaining = l.remaining - LEAST(o.remaining, $2 - (o.running - o.remaining))
+		FROM open o
+		WHERE l.id = o.id AND o.running - o.remaining < $2
+		RETURNING l.id, l.granted_at, LEAST(o.remaining, $2 - (o.running - o.remaining)) AS amount
+	), spent AS (
+		INSERT INTO coin_lot_spends (lot_id, transaction_id, amount)
+		SELECT id, $3, amount FROM taken
+	)
+	SELECT granted_at, amount FROM taken ORDER BY granted_at, id;`, name, amount, transactionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	portions := make([]lotPortion, 0)
+	taken := 0
+	for rows.Next() {
+		var p lotPortion
+		if err := rows.Scan(&p.grantedAt, &p.amount); err != nil {
+			return nil, err
+		}
+		portions = append(portions, p)
+		taken += p.amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if taken != amount {
+		return nil, fmt.Errorf("%w: %s", errLotsShort, name)
+	}
+
+	return portions, nil
+}
+
+// refundedLots gives back the lots the order was paid with, the newest first. Coins of
+// orders paid before lots existed count as granted at
+func refundedLots(ctx context.Context, q querier, orderID int, amount int, at time.Time) ([]lotPortion, error) {
+	rows, err := q.QueryContext(ctx, `
+	SELECT l.granted_at, s.amount FROM coin_lot_spends s
+	JOIN coin_lots l ON l.id = s.lot_id
+	JOIN ledger_transactions t ON t.id = s.transaction_id
+	WHERE t.kind=$1 AND t.order_id=$2
+	ORDER BY l.granted_at DESC, l.id DESC;`, ledger.KindPurchase, orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	portions := make([]lotPortion, 0)
+	left := amount
+	for rows.Next() && left > 0 {
+		var p lotPortion
+		if err := rows.Scan(&p.grantedAt, &p.amount); err != nil {
+			return nil, err
+		}
+		p.amount = min(p.amount, left)
+		portions = append(portions, p)
+		left -= p.amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if left > 0 {
+		portions = append(portions, lotPortion{grantedAt: at, amount: left})
+	}
+
+	return portions, nil
+}
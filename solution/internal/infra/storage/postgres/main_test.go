@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/infra/storage/migrations"
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+// testDB connects to the database in TEST_DATABASE_URL and migrates it, tests
+// needing Postgres are skipped without one. Tests share the database, so every
+// employee and item they create gets a unique name
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return db
+}
+
+var testSeq atomic.Int64
+
+// testName returns a name no other test uses, short enough for the name columns
+func testName(prefix string) string {
+	return fmt.Sprintf("%s-%d-%d", prefix, time.Now().UnixNano()%1e9, testSeq.Add(1))
+}
+
+// newTestEmployee registers an employee starting with coins from a welcome grant
+func newTestEmployee(t *testing.T, db *sql.DB, coins int) string {
+	t.Helper()
+
+	reg := &employee.Registration{
+		Name:     testName("emp"),
+		Password: "password",
+		Channel:  employee.ChannelRegister,
+		Grant:    employee.WelcomeGrant{Amount: coins},
+	}
+	name, err := NewEmployeeRepository(db).SaveEmployee(context.Background(), reg)
+	if err != nil {
+		t.Fatalf("save employee: %v", err)
+	}
+
+	return name
+}
+
+// newTestItem adds a catalog item, a nil stock makes it unlimited
+func newTestItem(t *testing.T, db *sql.DB, price int, stock *int) *shop.Item {
+	t.Helper()
+
+	item, err := NewShopRepository(db).SaveItem(context.Background(), &shop.Item{
+		Name:   testName("item"),
+		Price:  price,
+		Active: true,
+		Stock:  stock,
+	})
+	if err != nil {
+		t.Fatalf("save item: %v", err)
+	}
+
+	return item
+}
+
+func testCoins(t *testing.T, db *sql.DB, name string) int {
+	t.Helper()
+
+	emp, err := NewEmployeeRepository(db).GetEmployee(context.Background(), name)
+	if err != nil {
+		t.Fatalf("get employee: %v", err)
+	}
+
+	return emp.Coins
+}
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/ledger"
+	"github.com/wdsjk/avito-shop/internal/order"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+)
+
+type OrderRepository struct {
+	db *sql.DB
+}
+
+func NewOrderRepository(db *sql.DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+func (r *OrderRepository) GetOrder(ctx context.Context, name string, id int) (*order.Order, error) {
+	const op = "infra.storage.postgres.GetOrder"
+
+	var ord order.Order
+	err := r.db.QueryRowContext(ctx, `
+	SELECT id, employee_name, total, status, created_at, cancelled_at, cancelled_by
+	FROM orders WHERE id=$1 AND employee_name=$2;`, id, name,
+	).Scan(&ord.ID, &ord.EmployeeName, &ord.Total, &ord.Status, &ord.CreatedAt, &ord.CancelledAt, &ord.CancelledBy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", op, ErrOrderNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := loadOrderLines(ctx, r.db, []*order.Order{&ord}); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &ord, nil
+}
+
+func (r *OrderRepository) ListOrders(ctx context.Context, name string, limit, offset int) ([]*order.Order, int, error) {
+	const op = "infra.storage.postgres.ListOrders"
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM orders WHERE employee_name=$1;`, name).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT id, employee_name, total, status, created_at, cancelled_at, cancelled_by
+	FROM orders WHERE employee_name=$1
+	ORDER BY id DESC
+	LIMIT $2 OFFSET $3;`, name, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var orders []*order.Order
+	for rows.Next() {
+		var ord order.Order
+		if err := rows.Scan(&ord.ID, &ord.EmployeeName, &ord.Total, &ord.Status, &ord.CreatedAt, &ord.CancelledAt, &ord.CancelledBy); err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		orders = append(orders, &ord)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := loadOrderLines(ctx, r.db, orders); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return orders, total, nil
+}
+
+func (r *OrderRepository) CancelOrder(ctx context.Context, id int, cancel order.Cancellation) (*order.Order, error) {
+	const op = "infra.storage.postgres.CancelOrder"
+
+	var ord order.Order
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		// the order row lock serializes concurrent cancellations of the same order
+		err := tx.QueryRowContext(ctx, `
+		SELECT id, employee_name, total, status, created_at, cancelled_at, cancelled_by
+		FROM orders WHERE id=$1 FOR UPDATE;`, id,
+		).Scan(&ord.ID, &ord.EmployeeName, &ord.Total, &ord.Status, &ord.CreatedAt, &ord.CancelledAt, &ord.CancelledBy)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		if cancel.Owner != "" && cancel.Owner != ord.EmployeeName {
+			return ErrOrderNotFound
+		}
+
+		if err := loadOrderLines(ctx, tx, []*order.Order{&ord}); err != nil {
+			return err
+		}
+
+		if ord.Status == order.StatusCancelled {
+			return nil
+		}
+		if !cancel.CreatedAfter.IsZero() && ord.CreatedAt.Before(cancel.CreatedAfter) {
+			return order.ErrCancelWindowExpired
+		}
+
+		emp, err := lockEmployee(ctx, tx, ord.EmployeeName)
+		if err != nil {
+			return err
+		}
+
+		if emp.Inventory == nil {
+			emp.Inventory = make(employee.Inventory)
+		}
+		// lines come sorted by item, the order placeOrder locks stock rows in
+		for _, line := range ord.Items {
+			emp.Inventory[line.Item] = emp.Inventory[line.Item] - line.Quantity
+			if emp.Inventory[line.Item] <= 0 {
+				delete(emp.Inventory, line.Item)
+			}
+
+			// unlimited or deleted items have nothing to give back to
+			_, err := tx.ExecContext(ctx, `UPDATE catalog_items SET stock=stock+$1 WHERE name=$2 AND stock IS NOT NULL;`, line.Quantity, line.Item)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE employees SET coins=coins+$1, bought_items=$2 WHERE name=$3;`, ord.Total, emp.Inventory, ord.EmployeeName)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, `
+		UPDATE orders SET status=$1, cancelled_at=now(), cancelled_by=$2
+		WHERE id=$3
+		RETURNING status, cancelled_at, cancelled_by;`, order.StatusCancelled, cancel.Actor, ord.ID,
+		).Scan(&ord.Status, &ord.CancelledAt, &ord.CancelledBy)
+		if err != nil {
+			return err
+		}
+
+		return postLedger(ctx, tx, &ledger.Transaction{Kind: ledger.KindRefund, OrderID: &ord.ID}, ledger.Movement{
+			From:   ledger.AccountShop,
+			To:     ledger.EmployeeAccount(ord.EmployeeName),
+			Amount: ord.Total,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &ord, nil
+}
+
+// loadOrderLines fills Items of every order with a single query
+func loadOrderLines(ctx context.Context, q querier, orders []*order.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*order.Order, len(orders))
+	ids := make([]int, 0, len(orders))
+	for _, ord := range orders {
+		byID[ord.ID] = ord
+		ids = append(ids, ord.ID)
+	}
+
+	rows, err := q.QueryContext(ctx, `
+	SELECT order_id, item, quantity, unit_price
+	FROM order_items WHERE order_id = ANY($1)
+	ORDER BY order_id, item;`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			orderID int
+			line    order.Line
+		)
+		if err := rows.Scan(&orderID, &line.Item, &line.Quantity, &line.UnitPrice); err != nil {
+			return err
+		}
+		byID[orderID].Items = append(byID[orderID].Items, &line)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wdsjk/avito-shop/internal/scheduler"
+)
+
+type SchedulerRepository struct {
+	db *sql.DB
+}
+
+func NewSchedulerRepository(db *sql.DB) *SchedulerRepository {
+	return &SchedulerRepository{db: db}
+}
+
+// Lock takes a session level advisory lock, so it holds on to a connection until unlock.
+// If the replica dies the connection closes and Postgres releases the lock by itself
+func (r *SchedulerRepository) Lock(ctx context.Context, job string) (func(), bool, error) {
+	const op = "infra.storage.postgres.LockJob"
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var ok bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtextextended('job:' || $1, 0));`, job).Scan(&ok)
+	if err != nil || !ok {
+		conn.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// a failed unlock still closes the connection, which drops the lock with the session
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtextextended('job:' || $1, 0));`, job)
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
+
+func (r *SchedulerRepository) LastRun(ctx context.Context, job string) (*scheduler.Run, error) {
+	const op = "infra.storage.postgres.LastJobRun"
+
+	var run scheduler.Run
+	err := r.db.QueryRowContext(ctx, `
+	SELECT id, job, scheduled_at, status, attempts, error, started_at, finished_at
+	FROM job_runs WHERE job=$1 ORDER BY scheduled_at DESC LIMIT 1;`, job,
+	).Scan(&run.ID, &run.Job, &run.ScheduledAt, &run.Status, &run.Attempts, &run.Error, &run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &run, nil
+}
+
+func (r *SchedulerRepository) StartRun(ctx context.Context, run *scheduler.Run) error {
+	const op = "infra.storage.postgres.StartJobRun"
+
+	run.Status = scheduler.StatusRunning
+	err := r.db.QueryRowContext(ctx, `
+	INSERT INTO job_runs (job, scheduled_at, status, attempts, started_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (job, scheduled_at) DO UPDATE SET
+		status=EXCLUDED.status, attempts=EXCLUDED.attempts, error='', started_at=EXCLUDED.started_at, finished_at=NULL
+	RETURNING id;`,
+		run.Job, run.ScheduledAt, run.Status, run.Attempts, run.StartedAt,
+	).Scan(&run.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *SchedulerRepository) FinishRun(ctx context.Context, run *scheduler.Run) error {
+	const op = "infra.storage.postgres.FinishJobRun"
+
+	_, err := r.db.ExecContext(ctx, `UPDATE job_runs SET status=$2, error=$3, finished_at=$4 WHERE id=$1;`,
+		run.ID, run.Status, run.Error, run.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/session"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) SaveRefreshToken(ctx context.Context, token *session.RefreshToken) error {
+	const op = "infra.storage.postgres.SaveRefreshToken"
+
+	if err := saveRefreshToken(ctx, r.db, token); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, hash string, next *session.RefreshToken) (*session.RefreshToken, error) {
+	const op = "infra.storage.postgres.RotateRefreshToken"
+
+	var prev session.RefreshToken
+	reused := false
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+		SELECT id, family_id, employee_name, token_hash, access_jti, access_expires_at, created_at, expires_at, used_at, revoked_at
+		FROM refresh_tokens WHERE token_hash=$1 FOR UPDATE;`, hash,
+		).Scan(&prev.ID, &prev.FamilyID, &prev.EmployeeName, &prev.TokenHash, &prev.AccessJTI, &prev.AccessExpiresAt,
+			&prev.CreatedAt, &prev.ExpiresAt, &prev.UsedAt, &prev.RevokedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return session.ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if prev.RevokedAt != nil || !prev.ExpiresAt.After(time.Now()) {
+			return session.ErrInvalidRefreshToken
+		}
+
+		// a rotated token showing up again means it leaked, whoever holds the family loses it.
+		// The revocation has to be committed, so the error is only reported after the transaction
+		if prev.UsedAt != nil {
+			reused = true
+			return revokeFamily(ctx, tx, prev.FamilyID)
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at=now() WHERE id=$1;`, prev.ID)
+		if err != nil {
+			return err
+		}
+
+		next.FamilyID = prev.FamilyID
+		next.EmployeeName = prev.EmployeeName
+		return saveRefreshToken(ctx, tx, next)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if reused {
+		return nil, fmt.Errorf("%s: %w", op, session.ErrRefreshTokenReused)
+	}
+
+	return &prev, nil
+}
+
+func (r *SessionRepository) RevokeFamily(ctx context.Context, hash string) error {
+	const op = "infra.storage.postgres.RevokeRefreshTokenFamily"
+
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		var family string
+		err := tx.QueryRowContext(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash=$1;`, hash).Scan(&family)
+		if err != nil {
+			// logging out with an unknown token has nothing to revoke
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		return revokeFamily(ctx, tx, family)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "infra.storage.postgres.RevokeAccessToken"
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING;`, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	const op = "infra.storage.postgres.IsAccessTokenRevoked"
+
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti=$1);`, jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
+
+func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	const op = "infra.storage.postgres.DeleteExpiredSessions"
+
+	var total int64
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM revoked_tokens WHERE expires_at <= now();`,
+			`DELETE FROM refresh_tokens WHERE expires_at <= now();`,
+		} {
+			res, err := tx.ExecContext(ctx, query)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			total += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return total, nil
+}
+
+func saveRefreshToken(ctx context.Context, q querier, token *session.RefreshToken) error {
+	return q.QueryRowContext(ctx, `
+	INSERT INTO refresh_tokens (family_id, employee_name, token_hash, access_jti, access_expires_at, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id;`,
+		token.FamilyID, token.EmployeeName, token.TokenHash, token.AccessJTI, token.AccessExpiresAt, token.CreatedAt, token.ExpiresAt,
+	).Scan(&token.ID)
+}
+
+// revokeFamily revokes every refresh token of the family and denylists the access tokens that may still be valid
+func revokeFamily(ctx context.Context, q querier, family string) error {
+	_, err := q.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at=now() WHERE family_id=$1 AND revoked_at IS NULL;`, family)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `
+	INSERT INTO revoked_tokens (jti, expires_at)
+	SELECT access_jti, access_expires_at FROM refresh_tokens
+	WHERE family_id=$1 AND access_expires_at > now()
+	ON CONFLICT (jti) DO NOTHING;`, family)
+	return err
+}
@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+var (
+	ErrItemExists = errors.New("item already exists")
+	ErrOutOfStock = errors.New("item is out of stock")
+)
+
+type ShopRepository struct {
+	db *sql.DB
+}
+
+func NewShopRepository(db *sql.DB) *ShopRepository {
+	return &ShopRepository{db: db}
+}
+
+func (r *ShopRepository) SaveItem(ctx context.Context, item *shop.Item) (*shop.Item, error) {
+	const op = "infra.storage.postgres.SaveItem"
+
+	var saved shop.Item
+	err := r.db.QueryRowContext(ctx, `
+	INSERT INTO catalog_items (name, price, description, active, stock)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, name, price, description, active, stock, created_at, updated_at;`,
+		item.Name, item.Price, item.Description, item.Active, item.Stock,
+	).Scan(&saved.ID, &saved.Name, &saved.Price, &saved.Description, &saved.Active, &saved.Stock, &saved.CreatedAt, &saved.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%s: %w", op, ErrItemExists)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &saved, nil
+}
+
+func (r *ShopRepository) UpdateItem(ctx context.Context, item *shop.Item, setStock bool) (*shop.Item, error) {
+	const op = "infra.storage.postgres.UpdateItem"
+
+	var updated shop.Item
+	err := r.db.QueryRowContext(ctx, `
+	UPDATE catalog_items SET price=$1, description=$2, active=$3,
+		stock=CASE WHEN $5 THEN $6 ELSE stock END, updated_at=now()
+	WHERE name=$4
+	RETURNING id, name, price, description, active, stock, created_at, updated_at;`,
+		item.Price, item.Description, item.Active, item.Name, setStock, item.Stock,
+	).Scan(&updated.ID, &updated.Name, &updated.Price, &updated.Description, &updated.Active, &updated.Stock, &updated.CreatedAt, &updated.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", op, ErrItemNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &updated, nil
+}
+
+func (r *ShopRepository) DeleteItem(ctx context.Context, name string) error {
+	const op = "infra.storage.postgres.DeleteItem"
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM catalog_items WHERE name=$1;`, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, ErrItemNotFound)
+	}
+
+	return nil
+}
+
+func (r *ShopRepository) GetItem(ctx context.Context, name string) (*shop.Item, error) {
+	const op = "infra.storage.postgres.GetItem"
+
+	var item shop.Item
+	err := r.db.QueryRowContext(ctx, `
+	SELECT id, name, price, description, active, stock, created_at, updated_at
+	FROM catalog_items WHERE name=$1;`, name,
+	).Scan(&item.ID, &item.Name, &item.Price, &item.Description, &item.Active, &item.Stock, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", op, ErrItemNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &item, nil
+}
+
+func (r *ShopRepository) GetItems(ctx context.Context, names []string) ([]*shop.Item, error) {
+	const op = "infra.storage.postgres.GetItems"
+
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT id, name, price, description, active, stock, created_at, updated_at
+	FROM catalog_items WHERE name = ANY($1);`, names)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var items []*shop.Item
+	for rows.Next() {
+		var item shop.Item
+		err := rows.Scan(&item.ID, &item.Name, &item.Price, &item.Description, &item.Active, &item.Stock, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+func (r *ShopRepository) ListItems(ctx context.Context, filter shop.ListFilter) ([]*shop.Item, int, error) {
+	const op = "infra.storage.postgres.ListItems"
+
+	var (
+		conds []string
+		args  []any
+	)
+	if filter.MinPrice != nil {
+		args = append(args, *filter.MinPrice)
+		conds = append(conds, fmt.Sprintf("price >= $%d", len(args)))
+	}
+	if filter.MaxPrice != nil {
+		args = append(args, *filter.MaxPrice)
+		conds = append(conds, fmt.Sprintf("price <= $%d", len(args)))
+	}
+	if filter.Available != nil {
+		args = append(args, *filter.Available)
+		conds = append(conds, fmt.Sprintf("(active AND (stock IS NULL OR stock > 0)) = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	// filter.Sort is validated by the service, the map also guards against injection
+	orderBy, ok := map[string]string{
+		shop.SortByName:      "name ASC",
+		shop.SortByPriceAsc:  "price ASC, name ASC",
+		shop.SortByPriceDesc: "price DESC, name ASC",
+	}[filter.Sort]
+	if !ok {
+		orderBy = "name ASC"
+	}
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM catalog_items `+where+`;`, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+	SELECT id, name, price, description, active, stock, created_at, updated_at
+	FROM catalog_items %s
+	ORDER BY %s
+	LIMIT $%d OFFSET $%d;`, where, orderBy, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var items []*shop.Item
+	for rows.Next() {
+		var item shop.Item
+		err := rows.Scan(&item.ID, &item.Name, &item.Price, &item.Description, &item.Active, &item.Stock, &item.CreatedAt, &item.UpdatedAt)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, total, nil
+}
+
+func (r *ShopRepository) Restock(ctx context.Context, name string, quantity int) (*shop.Item, error) {
+	const op = "infra.storage.postgres.Restock"
+
+	// an unlimited item (NULL stock) stays unlimited, restocking it is an error rather than a cap
+	var item shop.Item
+	err := r.db.QueryRowContext(ctx, `
+	UPDATE catalog_items SET stock=stock+$1, updated_at=now()
+	WHERE name=$2 AND stock IS NOT NULL
+	RETURNING id, name, price, description, active, stock, created_at, updated_at;`,
+		quantity, name,
+	).Scan(&item.ID, &item.Name, &item.Price, &item.Description, &item.Active, &item.Stock, &item.CreatedAt, &item.UpdatedAt)
+	if err == sql.ErrNoRows {
+		var exists bool
+		err = r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM catalog_items WHERE name=$1);`, name).Scan(&exists)
+		if err == nil && exists {
+			return nil, fmt.Errorf("%s: %w", op, shop.ErrUnlimitedStock)
+		}
+		if err == nil {
+			return nil, fmt.Errorf("%s: %w", op, ErrItemNotFound)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &item, nil
+}
+
+// takeStock atomically reserves quantity units of an item inside the purchase
+// transaction, unlimited items (NULL stock) are never out of stock
+func takeStock(ctx context.Context, q querier, name string, quantity int) error {
+	res, err := q.ExecContext(ctx, `
+	UPDATE catalog_items SET stock=stock-$1
+	WHERE name=$2 AND (stock IS NULL OR stock >= $1);`, quantity, name)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrOutOfStock
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+func TestConcurrentPurchasesOfLimitedStock(t *testing.T) {
+	db := testDB(t)
+	repo := NewEmployeeRepository(db)
+	ctx := context.Background()
+
+	const (
+		stock  = 3
+		buyers = 10
+	)
+	item := newTestItem(t, db, 1, ptr(stock))
+
+	// every buyer is a different employee, so only the stock row is contended
+	names := make([]string, buyers)
+	for i := range names {
+		names[i] = newTestEmployee(t, db, 10)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.BuyItem(ctx, name, item, 1)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	bought, outOfStock := 0, 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			bought++
+		case errors.Is(err, ErrOutOfStock):
+			outOfStock++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if bought != stock || outOfStock != buyers-stock {
+		t.Errorf("%d bought and %d out of stock, want %d and %d", bought, outOfStock, stock, buyers-stock)
+	}
+
+	got, err := NewShopRepository(db).GetItem(ctx, item.Name)
+	if err != nil {
+		t.Fatalf("get item: %v", err)
+	}
+	if got.Stock == nil || *got.Stock != 0 {
+		t.Errorf("stock is %v, want 0", got.Stock)
+	}
+}
+
+func TestRestock(t *testing.T) {
+	db := testDB(t)
+	repo := NewShopRepository(db)
+	ctx := context.Background()
+
+	limited := newTestItem(t, db, 1, ptr(2))
+	item, err := repo.Restock(ctx, limited.Name, 5)
+	if err != nil {
+		t.Fatalf("restock: %v", err)
+	}
+	if item.Stock == nil || *item.Stock != 7 {
+		t.Errorf("stock is %v, want 7", item.Stock)
+	}
+
+	unlimited := newTestItem(t, db, 1, nil)
+	if _, err := repo.Restock(ctx, unlimited.Name, 5); !errors.Is(err, shop.ErrUnlimitedStock) {
+		t.Errorf("restocking an unlimited item: got %v, want %v", err, shop.ErrUnlimitedStock)
+	}
+	item, err = repo.GetItem(ctx, unlimited.Name)
+	if err != nil {
+		t.Fatalf("get item: %v", err)
+	}
+	if item.Stock != nil {
+		t.Errorf("unlimited item got a stock of %d", *item.Stock)
+	}
+
+	if _, err := repo.Restock(ctx, testName("missing"), 5); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("restocking a missing item: got %v, want %v", err, ErrItemNotFound)
+	}
+}
+
+func ptr(v int) *int {
+	return &v
+}
+
+func TestUpdateItemStock(t *testing.T) {
+	db := testDB(t)
+	repo := NewShopRepository(db)
+	ctx := context.Background()
+
+	item := newTestItem(t, db, 1, nil)
+	update := func(stock *int, setStock bool) *shop.Item {
+		updated, err := repo.UpdateItem(ctx, &shop.Item{Name: item.Name, Price: 2, Active: true, Stock: stock}, setStock)
+		if err != nil {
+			t.Fatalf("update item: %v", err)
+		}
+		return updated
+	}
+
+	// an unlimited item gets a limit, after which it can be restocked
+	if got := update(ptr(5), true); got.Stock == nil || *got.Stock != 5 {
+		t.Fatalf("stock is %v, want 5", got.Stock)
+	}
+	if _, err := repo.Restock(ctx, item.Name, 3); err != nil {
+		t.Fatalf("restock: %v", err)
+	}
+	if got := update(nil, false); got.Stock == nil || *got.Stock != 8 {
+		t.Errorf("update without a stock changed it to %v, want 8", got.Stock)
+	}
+	if got := update(nil, true); got.Stock != nil {
+		t.Errorf("stock is %d, want unlimited", *got.Stock)
+	}
+}
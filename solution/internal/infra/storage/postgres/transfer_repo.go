@@ -5,7 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/wdsjk/avito-shop/internal/ledger"
 	"github.com/wdsjk/avito-shop/internal/transfer"
 )
 
@@ -24,36 +27,174 @@ func NewTransferRepository(db *sql.DB) *TransferRepository {
 func (r *TransferRepository) SaveTransfer(ctx context.Context, senderName, receiverName string, amount int) error {
 	const op = "infra.storage.postgres.SaveTransfer"
 
-	stmt, err := r.db.PrepareContext(ctx, "INSERT INTO transfers (sender_name, receiver_name, amount) VALUES ($1, $2, $3)")
+	_, err := saveTransfer(ctx, r.db, senderName, receiverName, amount, transfer.Note{})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.ExecContext(ctx, senderName, receiverName, amount)
+	return nil
+}
+
+func (r *TransferRepository) GetTransferSummary(ctx context.Context, name string) ([]*transfer.Summary, error) {
+	const op = "infra.storage.postgres.GetTransferSummary"
+
+	// each half is served by the index on its name column, welcome grants
+	// only exist in the ledger and are received from a counterpart of their own
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT receiver_name AS counterpart, $2::text AS direction, SUM(amount), COUNT(*)
+	FROM transfers
+	WHERE sender_name=$1 AND receiver_name <> ''
+	GROUP BY receiver_name
+	UNION ALL
+	SELECT sender_name AS counterpart, $3::text AS direction, SUM(amount), COUNT(*)
+	FROM transfers
+	WHERE receiver_name=$1 AND sender_name <> ''
+	GROUP BY sender_name
+	UNION ALL
+	SELECT $4::text AS counterpart, $3::text AS direction, SUM(e.amount), COUNT(*)
+	FROM ledger_entries e JOIN ledger_transactions t ON t.id = e.transaction_id
+	WHERE e.account=$5 AND t.kind=$6
+	GROUP BY 1
+	ORDER BY direction, counterpart;`,
+		name, transfer.DirectionSent, transfer.DirectionReceived,
+		transfer.CounterpartWelcomeGrant, ledger.EmployeeAccount(name), ledger.KindIssuance,
+	)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer rows.Close()
 
-	return nil
+	var summaries []*transfer.Summary
+	for rows.Next() {
+		var s transfer.Summary
+		if err := rows.Scan(&s.Counterpart, &s.Direction, &s.Amount, &s.Count); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		summaries = append(summaries, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return summaries, nil
+}
+
+func (r *TransferRepository) ListTransfers(ctx context.Context, name string, filter transfer.ListFilter) ([]*transfer.Transfer, error) {
+	const op = "infra.storage.postgres.ListTransfers"
+
+	// rows involving the shop sentinel are purchases from before orders existed
+	conds := []string{"sender_name <> ''", "receiver_name <> ''"}
+	args := []any{name}
+	switch filter.Direction {
+	case transfer.DirectionSent:
+		conds = append(conds, "sender_name = $1")
+	case transfer.DirectionReceived:
+		conds = append(conds, "receiver_name = $1")
+	default:
+		conds = append(conds, "(sender_name = $1 OR receiver_name = $1)")
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conds = append(conds, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conds = append(conds, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		conds = append(conds, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+	SELECT id, sender_name, receiver_name, amount, message, tag, created_at
+	FROM transfers
+	WHERE %s
+	ORDER BY created_at DESC, id DESC
+	LIMIT $%d;`, strings.Join(conds, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var transfers []*transfer.Transfer
+	for rows.Next() {
+		var t transfer.Transfer
+		if err := rows.Scan(&t.ID, &t.SenderName, &t.ReceiverName, &t.Amount, &t.Message, &t.Tag, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		transfers = append(transfers, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return transfers, nil
+}
+
+// saveTransfer writes a transfer row using q, which may be a transaction
+// that also holds the balance updates the row describes
+func saveTransfer(ctx context.Context, q querier, senderName, receiverName string, amount int, note transfer.Note) (int, error) {
+	var id int
+	err := q.QueryRowContext(ctx, "INSERT INTO transfers (sender_name, receiver_name, amount, message, tag) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		senderName, receiverName, amount, note.Message, note.Tag,
+	).Scan(&id)
+	return id, err
 }
 
-func (r *TransferRepository) GetTransfersByEmployee(ctx context.Context, name string) ([]*transfer.Transfer, error) {
+// transferStats collects what the transfer rules need about the sender's transfers since dayStart.
+// Purchases recorded as transfers to the shop don't count
+func transferStats(ctx context.Context, q querier, senderName, receiverName string, dayStart time.Time) (*transfer.Stats, error) {
+	var stats transfer.Stats
+	var lastSent, lastFromCounterpart sql.NullTime
+	err := q.QueryRowContext(ctx, `
+	SELECT
+		(SELECT COALESCE(SUM(amount), 0) FROM transfers WHERE sender_name=$1 AND receiver_name <> '' AND created_at >= $3),
+		(SELECT COUNT(*) FROM transfers WHERE sender_name=$1 AND receiver_name=$2 AND created_at >= $3),
+		(SELECT MAX(created_at) FROM transfers WHERE sender_name=$1 AND receiver_name <> ''),
+		(SELECT MAX(created_at) FROM transfers WHERE sender_name=$2 AND receiver_name=$1);`,
+		senderName, receiverName, dayStart,
+	).Scan(&stats.SentToday, &stats.ToCounterpartToday, &lastSent, &lastFromCounterpart)
+	if err != nil {
+		return nil, err
+	}
+	stats.LastSent = lastSent.Time
+	stats.LastFromCounterpart = lastFromCounterpart.Time
+
+	return &stats, nil
+}
+
+// GetTransfersByEmployee returns the latest limit transfers between the employee and colleagues.
+// Welcome grants are among them as transfers from CounterpartWelcomeGrant, with the ledger transaction's id
+func (r *TransferRepository) GetTransfersByEmployee(ctx context.Context, name string, limit int) ([]*transfer.Transfer, error) {
 	const op = "infra.storage.postgres.GetTransfersByEmployee"
 
-	stmt, err := r.db.PrepareContext(ctx, "SELECT id, sender_name, receiver_name, amount FROM transfers WHERE sender_name=$1 OR receiver_name=$1")
+	stmt, err := r.db.PrepareContext(ctx, `
+	SELECT id, sender_name, receiver_name, amount, message, tag, created_at
+	FROM transfers
+	WHERE (sender_name=$1 OR receiver_name=$1) AND sender_name <> '' AND receiver_name <> ''
+	UNION ALL
+	SELECT t.id, $3::text, $1, e.amount, '', '', t.created_at
+	FROM ledger_entries e JOIN ledger_transactions t ON t.id = e.transaction_id
+	WHERE e.account=$4 AND t.kind=$5
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2;`)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
 	var transfers []*transfer.Transfer
-	rows, err := stmt.QueryContext(ctx, name)
+	rows, err := stmt.QueryContext(ctx, name, limit, transfer.CounterpartWelcomeGrant, ledger.EmployeeAccount(name), ledger.KindIssuance)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var t transfer.Transfer
-		err := rows.Scan(&t.ID, &t.SenderName, &t.ReceiverName, &t.Amount)
+		err := rows.Scan(&t.ID, &t.SenderName, &t.ReceiverName, &t.Amount, &t.Message, &t.Tag, &t.CreatedAt)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return nil, fmt.Errorf("%s: %w", op, ErrTransferNotFound)
@@ -62,6 +203,9 @@ func (r *TransferRepository) GetTransfersByEmployee(ctx context.Context, name st
 		}
 		transfers = append(transfers, &t)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	return transfers, nil
 }
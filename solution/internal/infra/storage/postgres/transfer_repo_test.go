@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/transfer"
+)
+
+func TestGetTransfersByEmployeeIsLimited(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	sender := newTestEmployee(t, db, 10)
+	receiver := newTestEmployee(t, db, 0)
+	for amount := 1; amount <= 3; amount++ {
+		err := NewEmployeeRepository(db).TransferCoins(ctx, sender, receiver, amount, transfer.Note{}, transfer.Rules{})
+		if err != nil {
+			t.Fatalf("transfer: %v", err)
+		}
+	}
+
+	transfers, err := NewTransferRepository(db).GetTransfersByEmployee(ctx, receiver, 2)
+	if err != nil {
+		t.Fatalf("get transfers: %v", err)
+	}
+	if len(transfers) != 2 || transfers[0].Amount != 3 || transfers[1].Amount != 2 {
+		t.Errorf("got %d transfers, want the latest 2", len(transfers))
+	}
+}
+
+func TestCoinHistoryShowsWelcomeGrants(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	employees := NewEmployeeRepository(db)
+
+	name, err := employees.SaveEmployee(ctx, &employee.Registration{
+		Name:     testName("emp"),
+		Password: "password",
+		Channel:  employee.ChannelRegister,
+		Grant:    employee.WelcomeGrant{Amount: 100, Vesting: 50, VestAt: time.Now().Add(-time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("save employee: %v", err)
+	}
+	if _, err := employees.VestWelcomeGrants(ctx, time.Now()); err != nil {
+		t.Fatalf("vest welcome grants: %v", err)
+	}
+	colleague := newTestEmployee(t, db, 10)
+	if err := employees.TransferCoins(ctx, colleague, name, 5, transfer.Note{}, transfer.Rules{}); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+
+	service := transfer.NewTransferService(NewTransferRepository(db))
+	received := func(grouped bool) map[string][2]int {
+		history, err := service.GetCoinHistory(ctx, name, grouped)
+		if err != nil {
+			t.Fatalf("get coin history: %v", err)
+		}
+		sums := make(map[string][2]int) // counterpart -> coins, entries
+		for _, s := range history {
+			if s.Direction != transfer.DirectionReceived {
+				t.Errorf("unexpected %s entry with %s", s.Direction, s.Counterpart)
+				continue
+			}
+			sum := sums[s.Counterpart]
+			sums[s.Counterpart] = [2]int{sum[0] + s.Amount, sum[1] + 1}
+		}
+		return sums
+	}
+
+	grouped := received(true)
+	if got := grouped[transfer.CounterpartWelcomeGrant]; got != [2]int{150, 1} {
+		t.Errorf("grouped welcome grants: %d coins in %d entries, want 150 in 1", got[0], got[1])
+	}
+	if got := grouped[colleague]; got != [2]int{5, 1} {
+		t.Errorf("grouped transfers: %d coins in %d entries, want 5 in 1", got[0], got[1])
+	}
+
+	ungrouped := received(false)
+	if got := ungrouped[transfer.CounterpartWelcomeGrant]; got != [2]int{150, 2} {
+		t.Errorf("welcome grants: %d coins in %d entries, want 150 in 2", got[0], got[1])
+	}
+	if got := ungrouped[colleague]; got != [2]int{5, 1} {
+		t.Errorf("transfers: %d coins in %d entries, want 5 in 1", got[0], got[1])
+	}
+}
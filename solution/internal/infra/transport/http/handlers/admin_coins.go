@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	dbErr "github.com/wdsjk/avito-shop/internal/infra/storage/postgres"
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/ledger"
+	"github.com/wdsjk/avito-shop/internal/lib/mapper"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+)
+
+type AdminCoinsHandler struct {
+	ledgerService *ledger.LedgerService
+	valid         *validator.Validate
+	log           *slog.Logger
+}
+
+func NewAdminCoinsHandler(ledgerService *ledger.LedgerService, valid *validator.Validate, log *slog.Logger) *AdminCoinsHandler {
+	return &AdminCoinsHandler{
+		ledgerService: ledgerService,
+		valid:         valid,
+		log:           log,
+	}
+}
+
+// Adjust applies a batch of grants and clawbacks. The batch is either JSON or, with
+// Content-Type: text/csv, "employee,amount" lines with reason and dryRun in the query
+func (h *AdminCoinsHandler) Adjust(w http.ResponseWriter, r *http.Request) {
+	username, _ := r.Context().Value("username").(string)
+	defer r.Body.Close()
+
+	var batch *ledger.Batch
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		batch, err = parseAdjustmentsCSV(r)
+	} else {
+		batch, err = h.parseAdjustmentsJSON(r)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid batch: " + err.Error()))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+	batch.Actor = username
+
+	h.adjust(w, r, batch)
+}
+
+// AdjustEmployee grants coins to or claws them back from a single employee
+func (h *AdminCoinsHandler) AdjustEmployee(w http.ResponseWriter, r *http.Request) {
+	username, _ := r.Context().Value("username").(string)
+
+	var req handlers_dto.AdjustEmployeeCoinsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer r.Body.Close()
+	if err := h.valid.Struct(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.adjust(w, r, &ledger.Batch{
+		Adjustments: []*ledger.Adjustment{{Employee: chi.URLParam(r, "name"), Amount: req.Amount}},
+		Reason:      req.Reason,
+		Actor:       username,
+		DryRun:      req.DryRun,
+	})
+}
+
+func (h *AdminCoinsHandler) adjust(w http.ResponseWriter, r *http.Request, batch *ledger.Batch) {
+	res, err := h.ledgerService.Adjust(r.Context(), batch)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+
+		var adjErr *ledger.AdjustmentError
+		errors.As(err, &adjErr)
+
+		switch {
+		case errors.Is(err, ledger.ErrEmptyBatch), errors.Is(err, ledger.ErrBatchTooLarge),
+			errors.Is(err, ledger.ErrReasonRequired), errors.Is(err, ledger.ErrReasonTooLong),
+			errors.Is(err, ledger.ErrInvalidAdjustment), errors.Is(err, ledger.ErrDuplicateEmployee),
+			errors.Is(err, ledger.ErrReservedAdjustment):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr(err.Error()))
+		case adjErr != nil && errors.Is(err, dbErr.ErrEmpNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("employee not found: " + adjErr.Employee))
+		case adjErr != nil && errors.Is(err, dbErr.ErrNoCoins):
+			w.WriteHeader(http.StatusConflict)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not enough coins to claw back from " + adjErr.Employee))
+		default:
+			h.log.Error("failed to adjust balances", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to adjust balances"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !res.DryRun {
+		h.log.Info("balances adjusted",
+			"actor", batch.Actor, "kind", res.Kind, "transaction_id", res.TransactionID,
+			"employees", len(res.Adjustments), "total", res.Total,
+		)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(mapper.AdjustCoinsResponse(res))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminCoinsHandler) parseAdjustmentsJSON(r *http.Request) (*ledger.Batch, error) {
+	var req handlers_dto.AdjustCoinsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, errors.New("invalid JSON body")
+	}
+	if err := h.valid.Struct(&req); err != nil {
+		return nil, errors.New("invalid JSON body")
+	}
+
+	batch := &ledger.Batch{Reason: req.Reason, DryRun: req.DryRun}
+	for _, a := range req.Adjustments {
+		batch.Adjustments = append(batch.Adjustments, &ledger.Adjustment{Employee: a.Employee, Amount: a.Amount})
+	}
+
+	return batch, nil
+}
+
+// parseAdjustmentsCSV reads "employee,amount" records, a header line is skipped
+func parseAdjustmentsCSV(r *http.Request) (*ledger.Batch, error) {
+	batch := &ledger.Batch{Reason: r.URL.Query().Get("reason")}
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("invalid dryRun")
+		}
+		batch.DryRun = dryRun
+	}
+
+	reader := csv.NewReader(io.LimitReader(r.Body, 1<<20))
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		amount, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, record[1])
+		}
+		batch.Adjustments = append(batch.Adjustments, &ledger.Adjustment{Employee: strings.TrimSpace(record[0]), Amount: amount})
+
+		if len(batch.Adjustments) > ledger.MaxBatchSize {
+			return nil, ledger.ErrBatchTooLarge
+		}
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wdsjk/avito-shop/internal/employee"
+	dbErr "github.com/wdsjk/avito-shop/internal/infra/storage/postgres"
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/lib/mapper"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+)
+
+type AdminEmployeesHandler struct {
+	employeeService *employee.EmployeeService
+	log             *slog.Logger
+}
+
+func NewAdminEmployeesHandler(employeeService *employee.EmployeeService, log *slog.Logger) *AdminEmployeesHandler {
+	return &AdminEmployeesHandler{
+		employeeService: employeeService,
+		log:             log,
+	}
+}
+
+// Deactivate locks the employee out until they are reactivated, issued tokens stop working at once
+func (h *AdminEmployeesHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.employeeService.Deactivate)
+}
+
+func (h *AdminEmployeesHandler) Reactivate(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.employeeService.Reactivate)
+}
+
+func (h *AdminEmployeesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.employeeService.Delete)
+}
+
+func (h *AdminEmployeesHandler) changeStatus(
+	w http.ResponseWriter, r *http.Request,
+	change func(ctx context.Context, name string) (*employee.Employee, error),
+) {
+	emp, err := change(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case errors.Is(err, dbErr.ErrEmpNotFound), errors.Is(err, employee.ErrReservedAccount):
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not found"))
+		case errors.Is(err, employee.ErrStatusTransition):
+			w.WriteHeader(http.StatusConflict)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("employee status can't be changed"))
+		default:
+			h.log.Error("failed to change employee status", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to change employee status"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(mapper.EmployeeResponse(emp))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// CreateInvite issues a single use code for POST /api/register
+func (h *AdminEmployeesHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	username, _ := r.Context().Value("username").(string)
+
+	var req handlers_dto.CreateInviteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		var err error
+		ttl, err = time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid expiresIn"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	invite, err := h.employeeService.CreateInvite(r.Context(), username, req.Department, ttl)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, employee.ErrInvalidDepartment) {
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid department"))
+		} else {
+			h.log.Error("failed to create invite", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to create invite"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(mapper.InviteResponse(invite))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminEmployeesHandler) Roles(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	roles, err := h.employeeService.GetRoles(r.Context(), name)
+	if err != nil {
+		h.log.Error("failed to get roles", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		err = json.NewEncoder(w).Encode(utils.MakeErr("failed to get roles"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(mapper.RolesResponse(name, roles))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// GrantRole takes effect for the employee with their next login or token refresh
+func (h *AdminEmployeesHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	username, _ := r.Context().Value("username").(string)
+	h.changeRole(w, r, func(ctx context.Context, name, role string) error {
+		return h.employeeService.GrantRole(ctx, name, role, username)
+	})
+}
+
+func (h *AdminEmployeesHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, h.employeeService.RevokeRole)
+}
+
+func (h *AdminEmployeesHandler) changeRole(
+	w http.ResponseWriter, r *http.Request,
+	change func(ctx context.Context, name, role string) error,
+) {
+	err := change(r.Context(), chi.URLParam(r, "name"), chi.URLParam(r, "role"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case errors.Is(err, dbErr.ErrEmpNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not found"))
+		case errors.Is(err, employee.ErrUnknownRole):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("unknown role"))
+		default:
+			h.log.Error("failed to change role", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to change role"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.Roles(w, r)
+}
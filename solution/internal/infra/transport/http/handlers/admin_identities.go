@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/identity"
+	dbErr "github.com/wdsjk/avito-shop/internal/infra/storage/postgres"
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+)
+
+type AdminIdentitiesHandler struct {
+	identityService *identity.IdentityService
+	valid           *validator.Validate
+	log             *slog.Logger
+}
+
+func NewAdminIdentitiesHandler(identityService *identity.IdentityService, valid *validator.Validate, log *slog.Logger) *AdminIdentitiesHandler {
+	return &AdminIdentitiesHandler{
+		identityService: identityService,
+		valid:           valid,
+		log:             log,
+	}
+}
+
+// Link lets an existing employee log in at an identity provider, a first login
+// never links to an existing employee by itself
+func (h *AdminIdentitiesHandler) Link(w http.ResponseWriter, r *http.Request) {
+	var req handlers_dto.LinkIdentityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer r.Body.Close()
+	if err := h.valid.Struct(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err := h.identityService.Link(r.Context(), chi.URLParam(r, "provider"), req.Subject, chi.URLParam(r, "name"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case errors.Is(err, dbErr.ErrEmpNotFound), errors.Is(err, employee.ErrReservedAccount), errors.Is(err, identity.ErrUnknownProvider):
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not found"))
+		case errors.Is(err, identity.ErrInvalidSubject):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid subject"))
+		case errors.Is(err, identity.ErrIdentityConflict):
+			w.WriteHeader(http.StatusConflict)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("identity or employee is already linked"))
+		default:
+			h.log.Error("failed to link identity", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to link identity"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
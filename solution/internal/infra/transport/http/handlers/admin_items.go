@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	dbErr "github.com/wdsjk/avito-shop/internal/infra/storage/postgres"
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/lib/mapper"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+type AdminItemsHandler struct {
+	shopService *shop.ShopService
+	valid       *validator.Validate
+	log         *slog.Logger
+}
+
+func NewAdminItemsHandler(
+	shopService *shop.ShopService,
+	valid *validator.Validate,
+	log *slog.Logger,
+) *AdminItemsHandler {
+	return &AdminItemsHandler{
+		shopService: shopService,
+		valid:       valid,
+		log:         log,
+	}
+}
+
+func (h *AdminItemsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req handlers_dto.CreateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer r.Body.Close()
+	if err := h.valid.Struct(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	item, err := h.shopService.CreateItem(r.Context(), &shop.Item{
+		Name:        req.Name,
+		Price:       req.Price,
+		Description: req.Description,
+		Active:      active,
+		Stock:       req.Stock,
+	})
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, dbErr.ErrItemExists) {
+			w.WriteHeader(http.StatusConflict)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("item already exists"))
+		} else {
+			h.log.Error("failed to create item", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to create item"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(mapper.ItemResponse(item))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminItemsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "item")
+	if name == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("item param is required"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var req handlers_dto.UpdateItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer r.Body.Close()
+	if err := h.valid.Struct(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	item, err := h.shopService.UpdateItem(r.Context(), &shop.Item{
+		Name:        name,
+		Price:       req.Price,
+		Description: req.Description,
+		Active:      *req.Active,
+		Stock:       req.Stock.Value,
+	}, req.Stock.Set)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case errors.Is(err, dbErr.ErrItemNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not found"))
+		case errors.Is(err, shop.ErrBadQuantity):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("stock can't be negative"))
+		default:
+			h.log.Error("failed to update item", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to update item"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(mapper.ItemResponse(item))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminItemsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "item")
+	if name == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("item param is required"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err := h.shopService.DeleteItem(r.Context(), name)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, dbErr.ErrItemNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not found"))
+		} else {
+			h.log.Error("failed to delete item", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to delete item"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminItemsHandler) Restock(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "item")
+	if name == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("item param is required"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var req handlers_dto.RestockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer r.Body.Close()
+	if err := h.valid.Struct(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	item, err := h.shopService.Restock(r.Context(), name, req.Quantity)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case errors.Is(err, dbErr.ErrItemNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not found"))
+		case errors.Is(err, shop.ErrBadQuantity):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("quantity must be positive"))
+		case errors.Is(err, shop.ErrUnlimitedStock):
+			w.WriteHeader(http.StatusConflict)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("item stock is unlimited, set a stock with PUT first"))
+		default:
+			h.log.Error("failed to restock item", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to restock item"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(mapper.ItemResponse(item))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
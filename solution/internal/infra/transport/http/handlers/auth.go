@@ -5,37 +5,50 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/identity"
 	dbErr "github.com/wdsjk/avito-shop/internal/infra/storage/postgres"
 	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
 	"github.com/wdsjk/avito-shop/internal/lib/mapper"
 	"github.com/wdsjk/avito-shop/internal/lib/utils"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/wdsjk/avito-shop/internal/session"
 )
 
-var secretKey = []byte(os.Getenv("jwt_secret"))
-
 type AuthHandler struct {
 	employeeService *employee.EmployeeService
+	sessionService  *session.SessionService
+	identityService *identity.IdentityService
+	keys            *jwtkeys.KeySet
+	autoRegister    bool
 	valid           *validator.Validate
 	log             *slog.Logger
 }
 
 func NewAuthHandler(
 	employeeService *employee.EmployeeService,
+	sessionService *session.SessionService,
+	identityService *identity.IdentityService,
+	keys *jwtkeys.KeySet,
+	autoRegister bool,
 	valid *validator.Validate,
 	logger *slog.Logger,
 ) *AuthHandler {
 	return &AuthHandler{
 		employeeService: employeeService,
+		sessionService:  sessionService,
+		identityService: identityService,
+		keys:            keys,
+		autoRegister:    autoRegister,
 		valid:           valid,
 		log:             logger,
 	}
 }
 
+// Handle logs the employee in, unknown usernames are registered only if auto registration is on
 func (h *AuthHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	var req handlers_dto.AuthRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -60,47 +73,120 @@ func (h *AuthHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	emp, err := h.employeeService.GetEmployee(r.Context(), req.Username)
-	if errors.Is(err, dbErr.ErrEmpNotFound) {
-		name, err := h.employeeService.SaveEmployee(r.Context(), req.Username, req.Password)
-		if name == "" && err != nil {
-			h.log.Error("failed to save employee", "error", err)
-			w.Header().Set("Content-Type", "application/json")
+	_, err := h.employeeService.Authenticate(r.Context(), req.Username, req.Password)
+	if errors.Is(err, dbErr.ErrEmpNotFound) && h.autoRegister {
+		// legacy mode, the first login with an unknown username creates the account
+		_, err = h.employeeService.SaveEmployee(r.Context(), req.Username, req.Password, "", employee.ChannelAuth)
+		if errors.Is(err, dbErr.ErrEmpExists) {
+			// registered concurrently by another request, its password is unknown here
+			err = employee.ErrInvalidCredentials
+		}
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case errors.Is(err, dbErr.ErrEmpNotFound), errors.Is(err, employee.ErrInvalidCredentials):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid username or password"))
+		case errors.Is(err, employee.ErrDeactivated):
+			w.WriteHeader(http.StatusForbidden)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("account is deactivated"))
+		case errors.Is(err, employee.ErrNotAllowed), errors.Is(err, employee.ErrInvalidInvite), errors.Is(err, employee.ErrReservedAccount):
+			w.WriteHeader(http.StatusForbidden)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("registration is not allowed"))
+		default:
+			h.log.Error("failed to authenticate employee", "error", err)
 			w.WriteHeader(http.StatusInternalServerError)
-			err := json.NewEncoder(w).Encode(utils.MakeErr("failed to save employee"))
-			if err != nil {
-				h.log.Error("failed to encode response", "error", err)
-				http.Error(w, "failed to encode response", http.StatusInternalServerError)
-			}
-			return
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to authenticate employee"))
 		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
 
-		token, err := utils.GenerateJWT(req.Username, secretKey)
+	sess, err := h.sessionService.Start(r.Context(), req.Username)
+	h.writeSession(w, r, sess, err, http.StatusOK)
+}
+
+// Register creates an account, the employee is logged in right away
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req handlers_dto.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
 		if err != nil {
-			h.log.Error("failed to generate token", "error", err)
-			w.Header().Set("Content-Type", "application/json")
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer r.Body.Close()
+	if err := h.valid.Struct(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	_, err := h.employeeService.SaveEmployee(r.Context(), req.Username, req.Password, req.Invite, employee.ChannelRegister)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case errors.Is(err, dbErr.ErrEmpExists):
+			w.WriteHeader(http.StatusConflict)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("username is already taken"))
+		case errors.Is(err, employee.ErrReservedAccount):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("username is reserved"))
+		case errors.Is(err, employee.ErrNotAllowed):
+			w.WriteHeader(http.StatusForbidden)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("registration is not allowed for this username"))
+		case errors.Is(err, employee.ErrInvalidInvite):
+			w.WriteHeader(http.StatusForbidden)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid invite code"))
+		default:
+			h.log.Error("failed to save employee", "error", err)
 			w.WriteHeader(http.StatusInternalServerError)
-			err := json.NewEncoder(w).Encode(utils.MakeErr("failed to generate token"))
-			if err != nil {
-				h.log.Error("failed to encode response", "error", err)
-				http.Error(w, "failed to encode response", http.StatusInternalServerError)
-			}
-			return
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to save employee"))
 		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
 
+	sess, err := h.sessionService.Start(r.Context(), req.Username)
+	h.writeSession(w, r, sess, err, http.StatusCreated)
+}
+
+// Refresh trades a refresh token for a new access and refresh token pair
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req handlers_dto.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		err = json.NewEncoder(w).Encode(mapper.AuthResponse(token))
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
 		if err != nil {
 			h.log.Error("failed to encode response", "error", err)
 			http.Error(w, "failed to encode response", http.StatusInternalServerError)
 		}
 		return
-	} else if err != nil {
-		h.log.Error("failed to get employee info", "error", err)
+	}
+	defer r.Body.Close()
+	if err := h.valid.Struct(&req); err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		err = json.NewEncoder(w).Encode(utils.MakeErr("failed to get employee info"))
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
 		if err != nil {
 			h.log.Error("failed to encode response", "error", err)
 			http.Error(w, "failed to encode response", http.StatusInternalServerError)
@@ -108,11 +194,31 @@ func (h *AuthHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(emp.Password), []byte(req.Password))
+	sess, err := h.sessionService.Refresh(r.Context(), req.RefreshToken)
+	if err == nil {
+		// a session outlives neither deactivation nor deletion of its employee
+		var active bool
+		active, err = h.employeeService.IsActive(r.Context(), sess.EmployeeName)
+		if err == nil && !active {
+			err = session.ErrInvalidRefreshToken
+		}
+	}
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		err = json.NewEncoder(w).Encode(utils.MakeErr("invalid username or password"))
+
+		switch {
+		case errors.Is(err, session.ErrInvalidRefreshToken):
+			w.WriteHeader(http.StatusUnauthorized)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid refresh token"))
+		case errors.Is(err, session.ErrRefreshTokenReused):
+			h.log.Warn("refresh token reuse detected, session revoked")
+			w.WriteHeader(http.StatusUnauthorized)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid refresh token"))
+		default:
+			h.log.Error("failed to refresh session", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to refresh session"))
+		}
 		if err != nil {
 			h.log.Error("failed to encode response", "error", err)
 			http.Error(w, "failed to encode response", http.StatusInternalServerError)
@@ -120,20 +226,70 @@ func (h *AuthHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := utils.GenerateJWT(req.Username, secretKey)
+	h.writeSession(w, r, sess, nil, http.StatusOK)
+}
+
+// Logout revokes the access token of the request and the refresh token family passed in the body
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	jti, _ := r.Context().Value("jti").(string)
+	expiresAt, _ := r.Context().Value("token_exp").(time.Time)
+
+	var req handlers_dto.LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	err := h.sessionService.Logout(r.Context(), req.RefreshToken, jti, expiresAt)
 	if err != nil {
+		h.log.Error("failed to log out", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		err = json.NewEncoder(w).Encode(utils.MakeErr("failed to log out"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeSession signs the access token of a session that was just started or refreshed
+func (h *AuthHandler) writeSession(w http.ResponseWriter, r *http.Request, sess *session.Session, err error, status int) {
+	var roles []string
+	if err == nil {
+		roles, err = h.employeeService.GetRoles(r.Context(), sess.EmployeeName)
+	}
+	var token string
+	if err == nil {
+		token, err = utils.GenerateJWT(h.keys, sess.EmployeeName, roles, sess.AccessJTI, sess.AccessExpiresAt)
+	}
+	if err != nil {
+		h.log.Error("failed to generate token", "error", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		err := json.NewEncoder(w).Encode(utils.MakeErr("failed to generate token"))
 		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
 			http.Error(w, "failed to encode response", http.StatusInternalServerError)
 		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(mapper.AuthResponse(token))
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(mapper.AuthResponse(token, sess))
 	if err != nil {
 		h.log.Error("failed to encode response", "error", err)
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wdsjk/avito-shop/internal/employee"
+	"github.com/wdsjk/avito-shop/internal/identity"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+)
+
+// OIDCLogin redirects the user to the identity provider to log in there
+func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	redirect, err := h.identityService.Begin(r.Context(), chi.URLParam(r, "provider"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, identity.ErrUnknownProvider) {
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not found"))
+		} else {
+			h.log.Error("failed to start oidc login", "error", err)
+			w.WriteHeader(http.StatusBadGateway)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("identity provider is unavailable"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// OIDCCallback is where the identity provider sends the user back, it responds like a password login
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("login failed: " + e))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	name, err := h.identityService.Complete(r.Context(), chi.URLParam(r, "provider"), q.Get("state"), q.Get("code"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case errors.Is(err, identity.ErrUnknownProvider):
+			w.WriteHeader(http.StatusNotFound)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("not found"))
+		case errors.Is(err, identity.ErrInvalidState):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid or expired login state"))
+		case errors.Is(err, identity.ErrInvalidToken):
+			h.log.Warn("rejected id token", "error", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid id token"))
+		case errors.Is(err, employee.ErrDeactivated):
+			w.WriteHeader(http.StatusForbidden)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("account is deactivated"))
+		case errors.Is(err, identity.ErrNoUsername), errors.Is(err, identity.ErrIdentityConflict), errors.Is(err, employee.ErrReservedAccount):
+			h.log.Warn("identity can't be mapped to an employee", "error", err)
+			w.WriteHeader(http.StatusForbidden)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("identity can't be mapped to an employee"))
+		default:
+			h.log.Error("failed to complete oidc login", "error", err)
+			w.WriteHeader(http.StatusBadGateway)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to complete login"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	sess, err := h.sessionService.Start(r.Context(), name)
+	h.writeSession(w, r, sess, err, http.StatusOK)
+}
@@ -3,8 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/wdsjk/avito-shop/internal/employee"
@@ -16,19 +19,16 @@ import (
 
 type CoinHandler struct {
 	employeeService *employee.EmployeeService
-	transferService *transfer.TransferService
 	valid           *validator.Validate
 	log             *slog.Logger
 }
 
 func NewCoinHandler(
 	employeeService *employee.EmployeeService,
-	transferService *transfer.TransferService,
 	valid *validator.Validate, log *slog.Logger,
 ) *CoinHandler {
 	return &CoinHandler{
 		employeeService: employeeService,
-		transferService: transferService,
 		valid:           valid,
 		log:             log,
 	}
@@ -70,10 +70,12 @@ func (h *CoinHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.employeeService.TransferCoins(r.Context(), username, req.ToUser, req.Amount, h.transferService)
+	err := h.employeeService.TransferCoins(r.Context(), username, req.ToUser, req.Amount, req.Message, req.Tag)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 
+		var violation *transfer.RuleViolation
+
 		switch {
 		case errors.Is(err, dbErr.ErrEmpNotFound):
 			w.WriteHeader(http.StatusBadRequest)
@@ -90,6 +92,60 @@ func (h *CoinHandler) Handle(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "failed to encode response", http.StatusInternalServerError)
 			}
 			return
+		case errors.Is(err, employee.ErrInvalidAmount):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("amount must be positive"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		case errors.Is(err, employee.ErrSelfTransfer):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("can't send coins to yourself"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		case errors.Is(err, employee.ErrReservedAccount):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("recipient can't receive coins"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		case errors.Is(err, transfer.ErrMessageTooLong):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("message is too long"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		case errors.Is(err, transfer.ErrInvalidTag):
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid tag"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		case errors.As(err, &violation):
+			// limits that reset with time are rate limits, the others won't let the transfer through at all
+			if violation.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(violation.RetryAfter.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+			} else {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			}
+			err = json.NewEncoder(w).Encode(utils.MakeErr(transferRuleMessage(violation)))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
 		default:
 			h.log.Error("failed to get transfer info", "error", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -104,3 +160,20 @@ func (h *CoinHandler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+func transferRuleMessage(v *transfer.RuleViolation) string {
+	switch v.Rule {
+	case transfer.RuleMaxPerTransfer:
+		return fmt.Sprintf("at most %d coins can be sent at once", v.Limit)
+	case transfer.RuleMaxPerDay:
+		return fmt.Sprintf("at most %d coins can be sent per day", v.Limit)
+	case transfer.RuleMaxPerCounterpartPerDay:
+		return fmt.Sprintf("at most %d transfers to the same colleague per day", v.Limit)
+	case transfer.RuleCooldown:
+		return "sending coins again too soon"
+	case transfer.RuleReturnCooldown:
+		return "sending coins back too soon"
+	default:
+		return "transfer is not allowed"
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/wdsjk/avito-shop/internal/employee"
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/transfer"
+)
+
+// the service rejects these transfers before they reach the repository, the cases that
+// lead to each error are covered by its own tests
+func TestSendCoinErrorResponses(t *testing.T) {
+	service := employee.NewEmployeeService(nil, nil, employee.RegistrationPolicy{}, employee.WelcomePolicy{}, transfer.Rules{}, nil)
+	handler := NewCoinHandler(service, validator.New(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		message string
+	}{
+		{"invalid amount", `{"toUser": "bob", "amount": 0}`, http.StatusBadRequest, "amount must be positive"},
+		{"self transfer", `{"toUser": "alice", "amount": 10}`, http.StatusBadRequest, "can't send coins to yourself"},
+		{"reserved account", `{"toUser": " ", "amount": 10}`, http.StatusBadRequest, "recipient can't receive coins"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/sendCoin", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "username", "alice"))
+			rec := httptest.NewRecorder()
+			handler.Handle(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			var resp handlers_dto.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Errors != tt.message {
+				t.Errorf("message %q, want %q", resp.Errors, tt.message)
+			}
+		})
+	}
+}
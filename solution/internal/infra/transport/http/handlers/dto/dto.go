@@ -1,5 +1,12 @@
 package handlers_dto
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
+)
+
 type ErrorResponse struct {
 	Errors string `json:"errors"`
 }
@@ -14,12 +21,21 @@ type InfoResponse struct {
 		Received []struct {
 			FromUser string `json:"fromUser"`
 			Amount   int    `json:"amount"`
+			Message  string `json:"message,omitempty"`
+			Tag      string `json:"tag,omitempty"`
 		} `json:"received"`
 		Sent []struct {
-			ToUser string `json:"toUser"`
-			Amount int    `json:"amount"`
+			ToUser  string `json:"toUser"`
+			Amount  int    `json:"amount"`
+			Message string `json:"message,omitempty"`
+			Tag     string `json:"tag,omitempty"`
 		} `json:"sent"`
 	} `json:"coinHistory"`
+	// coins that expire soon, oldest first
+	ExpiringCoins []struct {
+		Amount    int       `json:"amount"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	} `json:"expiringCoins"`
 }
 
 type AuthRequest struct {
@@ -27,11 +43,212 @@ type AuthRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type RegisterRequest struct {
+	Username string `json:"username" validate:"required,max=50"`
+	Password string `json:"password" validate:"required,max=72"`
+	Invite   string `json:"invite,omitempty"`
+}
+
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken,omitempty"`
 }
 
 type SendCoinRequest struct {
-	ToUser string `json:"toUser" validate:"required"`
+	ToUser  string `json:"toUser" validate:"required"`
+	Amount  int    `json:"amount"` // checked by the service, so zero gets its own error
+	Message string `json:"message,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+}
+
+type CreateItemRequest struct {
+	Name        string `json:"name" validate:"required,max=50"`
+	Price       int    `json:"price" validate:"required,gt=0"`
+	Description string `json:"description"`
+	Active      *bool  `json:"active"`                           // true if omitted
+	Stock       *int   `json:"stock" validate:"omitempty,gte=0"` // unlimited if omitted
+}
+
+type UpdateItemRequest struct {
+	Price       int         `json:"price" validate:"required,gt=0"`
+	Description string      `json:"description"`
+	Active      *bool       `json:"active" validate:"required"`
+	Stock       NullableInt `json:"stock"` // unchanged if omitted, unlimited if null
+}
+
+// NullableInt tells an omitted field from an explicit null
+type NullableInt struct {
+	Set   bool
+	Value *int
+}
+
+func (n *NullableInt) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+
+	return json.Unmarshal(data, &n.Value)
+}
+
+type ItemResponse struct {
+	Name        string    `json:"name"`
+	Price       int       `json:"price"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	Stock       *int      `json:"stock"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type ItemsResponse struct {
+	Items []struct {
+		Name        string `json:"name"`
+		Price       int    `json:"price"`
+		Description string `json:"description"`
+		Available   bool   `json:"available"`
+		Stock       *int   `json:"stock"`
+	} `json:"items"`
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type RestockRequest struct {
+	Quantity int `json:"quantity" validate:"required,gt=0"`
+}
+
+type BuyRequest struct {
+	Quantity int `json:"quantity" validate:"omitempty,gt=0"` // 1 if omitted
+}
+
+type CartItemRequest struct {
+	Item     string `json:"item" validate:"required"`
+	Quantity int    `json:"quantity" validate:"omitempty,gt=0"` // 1 if omitted when adding, everything when removing
+}
+
+type CartResponse struct {
+	Items []struct {
+		Item      string `json:"item"`
+		Quantity  int    `json:"quantity"`
+		UnitPrice int    `json:"unitPrice"`
+		Available bool   `json:"available"`
+	} `json:"items"`
+	Total int `json:"total"`
+}
+
+type OrderResponse struct {
+	ID    int `json:"id"`
+	Items []struct {
+		Item      string `json:"item"`
+		Quantity  int    `json:"quantity"`
+		UnitPrice int    `json:"unitPrice"`
+	} `json:"items"`
+	Total       int        `json:"total"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty"`
+}
+
+type OrdersResponse struct {
+	Orders []*OrderResponse `json:"orders"`
+	Total  int              `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+type ReconciliationResponse struct {
+	OK         bool    `json:"ok"`
+	Total      int     `json:"total"`
+	Unbalanced []int64 `json:"unbalancedTransactions"`
+	Mismatches []struct {
+		Employee string `json:"employee"`
+		Cached   int    `json:"cached"`
+		Ledger   int    `json:"ledger"`
+	} `json:"mismatches"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+type TransfersResponse struct {
+	Transfers []struct {
+		ID        int       `json:"id"`
+		FromUser  string    `json:"fromUser"`
+		ToUser    string    `json:"toUser"`
+		Amount    int       `json:"amount"`
+		Message   string    `json:"message,omitempty"`
+		Tag       string    `json:"tag,omitempty"`
+		CreatedAt time.Time `json:"createdAt"`
+	} `json:"transfers"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type EmployeeResponse struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Department string    `json:"department,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type CreateInviteRequest struct {
+	ExpiresIn  string `json:"expiresIn,omitempty"`  // Go duration, empty never expires
+	Department string `json:"department,omitempty"` // decides the welcome grant of whoever registers with it
+}
+
+// LinkIdentityRequest names the account at the provider the employee logs in with
+type LinkIdentityRequest struct {
+	Subject string `json:"subject" validate:"required,max=255"`
+}
+
+type InviteResponse struct {
+	Code       string     `json:"code"`
+	Department string     `json:"department,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+type JWKSResponse struct {
+	Keys []jwtkeys.JWK `json:"keys"`
+}
+
+type RolesResponse struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+type AdjustCoinsRequest struct {
+	Reason      string `json:"reason" validate:"required"`
+	DryRun      bool   `json:"dryRun"`
+	Adjustments []struct {
+		Employee string `json:"employee" validate:"required"`
+		Amount   int    `json:"amount" validate:"required"`
+	} `json:"adjustments" validate:"required,min=1,dive"`
+}
+
+type AdjustEmployeeCoinsRequest struct {
 	Amount int    `json:"amount" validate:"required"`
+	Reason string `json:"reason" validate:"required"`
+	DryRun bool   `json:"dryRun"`
+}
+
+type AdjustCoinsResponse struct {
+	TransactionID int64  `json:"transactionId,omitempty"`
+	Kind          string `json:"kind"`
+	DryRun        bool   `json:"dryRun"`
+	Total         int    `json:"total"`
+	Adjustments   []struct {
+		Employee string `json:"employee"`
+		Amount   int    `json:"amount"`
+		Before   int    `json:"before"`
+		After    int    `json:"after"`
+	} `json:"adjustments"`
 }
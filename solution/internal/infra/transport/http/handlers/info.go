@@ -5,28 +5,33 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/wdsjk/avito-shop/internal/employee"
 	dbErr "github.com/wdsjk/avito-shop/internal/infra/storage/postgres"
 	"github.com/wdsjk/avito-shop/internal/lib/mapper"
 	"github.com/wdsjk/avito-shop/internal/lib/utils"
+	"github.com/wdsjk/avito-shop/internal/lot"
 	"github.com/wdsjk/avito-shop/internal/transfer"
 )
 
 type InfoHandler struct {
 	employeeService *employee.EmployeeService
 	transferService *transfer.TransferService
+	lotService      *lot.LotService
 	log             *slog.Logger
 }
 
 func NewInfoHandler(
 	employeeService *employee.EmployeeService,
 	transferService *transfer.TransferService,
+	lotService *lot.LotService,
 	log *slog.Logger,
 ) *InfoHandler {
 	return &InfoHandler{
 		employeeService: employeeService,
 		transferService: transferService,
+		lotService:      lotService,
 		log:             log,
 	}
 }
@@ -62,7 +67,23 @@ func (h *InfoHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ts, err := h.transferService.GetTransfersByEmployee(r.Context(), username)
+	// history is summed per colleague unless ?grouped=false asks for every transfer
+	grouped := true
+	if v := r.URL.Query().Get("grouped"); v != "" {
+		grouped, err = strconv.ParseBool(v)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid query parameters"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	history, err := h.transferService.GetCoinHistory(r.Context(), username, grouped)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -81,9 +102,22 @@ func (h *InfoHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expirations, err := h.lotService.Upcoming(r.Context(), username)
+	if err != nil {
+		h.log.Error("failed to get expiring coins", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		err = json.NewEncoder(w).Encode(utils.MakeErr("failed to get expiring coins"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(mapper.InfoResponse(emp, ts))
+	err = json.NewEncoder(w).Encode(mapper.InfoResponse(emp, history, expirations))
 	if err != nil {
 		h.log.Error("failed to encode response", "error", err)
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/wdsjk/avito-shop/internal/lib/mapper"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+type ItemsHandler struct {
+	shopService *shop.ShopService
+	log         *slog.Logger
+}
+
+func NewItemsHandler(shopService *shop.ShopService, log *slog.Logger) *ItemsHandler {
+	return &ItemsHandler{
+		shopService: shopService,
+		log:         log,
+	}
+}
+
+func (h *ItemsHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid query parameters"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	items, total, err := h.shopService.ListItems(r.Context(), filter)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, shop.ErrInvalidFilter) {
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid query parameters"))
+		} else {
+			h.log.Error("failed to list items", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to list items"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(mapper.ItemsResponse(items, total, filter))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// parseListFilter reads ?minPrice=&maxPrice=&available=&sort=&limit=&offset=,
+// leaving omitted parameters zero so the service can apply its defaults
+func parseListFilter(r *http.Request) (*shop.ListFilter, error) {
+	q := r.URL.Query()
+	filter := &shop.ListFilter{Sort: q.Get("sort")}
+
+	if v := q.Get("minPrice"); v != "" {
+		price, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.MinPrice = &price
+	}
+	if v := q.Get("maxPrice"); v != "" {
+		price, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.MaxPrice = &price
+	}
+	if v := q.Get("available"); v != "" {
+		available, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.Available = &available
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.Limit = limit
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
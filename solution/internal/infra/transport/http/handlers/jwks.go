@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
+	"github.com/wdsjk/avito-shop/internal/lib/mapper"
+)
+
+type JWKSHandler struct {
+	keys *jwtkeys.KeySet
+	log  *slog.Logger
+}
+
+func NewJWKSHandler(keys *jwtkeys.KeySet, log *slog.Logger) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+		log:  log,
+	}
+}
+
+// Handle publishes the public keys our access tokens are signed with, so other services can verify them
+func (h *JWKSHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// verifiers refetch on an unknown kid anyway, a short cache is enough
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(mapper.JWKSResponse(h.keys.JWKS()))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
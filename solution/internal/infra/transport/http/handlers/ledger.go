@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/wdsjk/avito-shop/internal/ledger"
+	"github.com/wdsjk/avito-shop/internal/lib/mapper"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+)
+
+type LedgerHandler struct {
+	ledgerService *ledger.LedgerService
+	log           *slog.Logger
+}
+
+func NewLedgerHandler(ledgerService *ledger.LedgerService, log *slog.Logger) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: ledgerService,
+		log:           log,
+	}
+}
+
+// Reconcile reports whether the ledger is consistent, inconsistencies are logged as errors
+func (h *LedgerHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	rec, err := h.ledgerService.Reconcile(r.Context())
+	if err != nil {
+		h.log.Error("failed to reconcile ledger", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		err = json.NewEncoder(w).Encode(utils.MakeErr("failed to reconcile ledger"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !rec.OK() {
+		h.log.Error("ledger is inconsistent",
+			"total", rec.Total,
+			"unbalanced", len(rec.Unbalanced),
+			"mismatches", len(rec.Mismatches),
+		)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(mapper.ReconciliationResponse(rec))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -3,32 +3,34 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/wdsjk/avito-shop/internal/employee"
 	dbErr "github.com/wdsjk/avito-shop/internal/infra/storage/postgres"
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
 	"github.com/wdsjk/avito-shop/internal/lib/utils"
 	"github.com/wdsjk/avito-shop/internal/shop"
-	"github.com/wdsjk/avito-shop/internal/transfer"
 )
 
 type ShopHandler struct {
 	employeeService *employee.EmployeeService
-	transferService *transfer.TransferService
-	shop            shop.Shop
+	valid           *validator.Validate
 	log             *slog.Logger
 }
 
 func NewShopHandler(
-	employeeService *employee.EmployeeService, transferService *transfer.TransferService,
-	shop shop.Shop, log *slog.Logger,
+	employeeService *employee.EmployeeService,
+	valid *validator.Validate,
+	log *slog.Logger,
 ) *ShopHandler {
 	return &ShopHandler{
 		employeeService: employeeService,
-		transferService: transferService,
-		shop:            shop,
+		valid:           valid,
 		log:             log,
 	}
 }
@@ -58,11 +60,51 @@ func (h *ShopHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.employeeService.BuyItem(r.Context(), username, item, h.shop, h.transferService)
+	// GET takes ?quantity=, POST may carry {"quantity": N} instead, both default to one unit
+	req := handlers_dto.BuyRequest{Quantity: 1}
+	if v := r.URL.Query().Get("quantity"); v != "" {
+		quantity, err := strconv.Atoi(v)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid quantity"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		}
+		req.Quantity = quantity
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		}
+		defer r.Body.Close()
+		if err := h.valid.Struct(&req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid JSON body"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	_, err := h.employeeService.BuyItem(r.Context(), username, item, req.Quantity)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		switch {
-		case errors.Is(err, dbErr.ErrEmpNotFound) || errors.Is(err, dbErr.ErrItemNotFound):
+		case errors.Is(err, dbErr.ErrEmpNotFound) || errors.Is(err, dbErr.ErrItemNotFound) || errors.Is(err, shop.ErrItemInactive):
 			w.WriteHeader(http.StatusBadRequest)
 			err := json.NewEncoder(w).Encode(utils.MakeErr("not found"))
 			if err != nil {
@@ -78,6 +120,22 @@ func (h *ShopHandler) Handle(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "failed to encode response", http.StatusInternalServerError)
 			}
 			return
+		case errors.Is(err, shop.ErrBadQuantity):
+			w.WriteHeader(http.StatusBadRequest)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid quantity"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
+		case errors.Is(err, dbErr.ErrOutOfStock):
+			w.WriteHeader(http.StatusConflict)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("out of stock"))
+			if err != nil {
+				h.log.Error("failed to encode response", "error", err)
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+			return
 		default:
 			w.WriteHeader(http.StatusInternalServerError)
 			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to buy item"))
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/lib/mapper"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+	"github.com/wdsjk/avito-shop/internal/transfer"
+)
+
+type TransfersHandler struct {
+	transferService *transfer.TransferService
+	log             *slog.Logger
+}
+
+func NewTransfersHandler(transferService *transfer.TransferService, log *slog.Logger) *TransfersHandler {
+	return &TransfersHandler{
+		transferService: transferService,
+		log:             log,
+	}
+}
+
+func (h *TransfersHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	username, ok := r.Context().Value("username").(string)
+	if !ok || username == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("unauthorized"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	filter, err := parseTransferFilter(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		err := json.NewEncoder(w).Encode(utils.MakeErr("invalid query parameters"))
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	page, err := h.transferService.ListTransfers(r.Context(), username, filter)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, transfer.ErrInvalidFilter) {
+			w.WriteHeader(http.StatusBadRequest)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("invalid query parameters"))
+		} else {
+			h.log.Error("failed to list transfers", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			err = json.NewEncoder(w).Encode(utils.MakeErr("failed to list transfers"))
+		}
+		if err != nil {
+			h.log.Error("failed to encode response", "error", err)
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(mapper.TransfersResponse(page))
+	if err != nil {
+		h.log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// parseTransferFilter reads ?direction=sent|received&from=&to=&cursor=&limit=,
+// from and to are RFC 3339 timestamps
+func parseTransferFilter(r *http.Request) (transfer.ListFilter, error) {
+	q := r.URL.Query()
+	filter := transfer.ListFilter{Direction: q.Get("direction")}
+
+	if v := q.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, err
+		}
+		filter.From = &from
+	}
+	if v := q.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, err
+		}
+		filter.To = &to
+	}
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := transfer.DecodeCursor(v)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = cursor
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, err
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
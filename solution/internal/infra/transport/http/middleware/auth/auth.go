@@ -3,66 +3,156 @@ package mwauth
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
 	"github.com/wdsjk/avito-shop/internal/lib/utils"
 )
 
-var secretKey = []byte(os.Getenv("jwt_secret"))
+// ActivityChecker tells whether an employee may still use the API
+type ActivityChecker interface {
+	IsActive(ctx context.Context, name string) (bool, error)
+}
 
-func Auth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		headerString := r.Header.Get("Authorization")
-		if headerString == "" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			err := json.NewEncoder(w).Encode(utils.MakeErr("missing authorization header"))
-			if err != nil {
-				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+// RevocationChecker tells whether an access token was revoked by its jti
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// New authenticates requests by their bearer token, revoked tokens and tokens of deactivated
+// or deleted employees are rejected even before they expire
+func New(keys *jwtkeys.KeySet, checker ActivityChecker, revocations RevocationChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headerString := r.Header.Get("Authorization")
+			if headerString == "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				err := json.NewEncoder(w).Encode(utils.MakeErr("missing authorization header"))
+				if err != nil {
+					http.Error(w, "failed to encode response", http.StatusInternalServerError)
+				}
+				return
 			}
-			return
-		}
 
-		parts := strings.Split(headerString, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid Authorization header format"))
-			if err != nil {
-				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			parts := strings.Split(headerString, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				err := json.NewEncoder(w).Encode(utils.MakeErr("invalid Authorization header format"))
+				if err != nil {
+					http.Error(w, "failed to encode response", http.StatusInternalServerError)
+				}
+				return
 			}
-			return
-		}
-		tokenString := parts[1]
+			tokenString := parts[1]
 
-		token, err := parseToken(tokenString)
-		if err != nil || !token.Valid {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			err := json.NewEncoder(w).Encode(utils.MakeErr("invalid token"))
+			token, err := parseToken(keys, tokenString)
+			if err != nil || !token.Valid {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				err := json.NewEncoder(w).Encode(utils.MakeErr("invalid token"))
+				if err != nil {
+					http.Error(w, "failed to encode response", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			ctx := r.Context()
+			var jti string
+			if claims, ok := token.Claims.(jwt.MapClaims); ok {
+				ctx = context.WithValue(ctx, "username", claims["username"])
+				jti, _ = claims["jti"].(string)
+				ctx = context.WithValue(ctx, "jti", jti)
+				var roles []string
+				if claimed, ok := claims["roles"].([]any); ok {
+					for _, role := range claimed {
+						if role, ok := role.(string); ok {
+							roles = append(roles, role)
+						}
+					}
+				}
+				ctx = context.WithValue(ctx, "roles", roles)
+				if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+					ctx = context.WithValue(ctx, "token_exp", exp.Time)
+				}
+			}
+
+			// tokens without a jti can't be revoked, so they are not accepted at all
+			revoked := true
+			if jti != "" {
+				revoked, err = revocations.IsRevoked(r.Context(), jti)
+				if err != nil {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusInternalServerError)
+					err := json.NewEncoder(w).Encode(utils.MakeErr("failed to check token"))
+					if err != nil {
+						http.Error(w, "failed to encode response", http.StatusInternalServerError)
+					}
+					return
+				}
+			}
+			if revoked {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				err := json.NewEncoder(w).Encode(utils.MakeErr("invalid token"))
+				if err != nil {
+					http.Error(w, "failed to encode response", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			username, _ := ctx.Value("username").(string)
+			active, err := checker.IsActive(r.Context(), username)
 			if err != nil {
-				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				err := json.NewEncoder(w).Encode(utils.MakeErr("failed to check account"))
+				if err != nil {
+					http.Error(w, "failed to encode response", http.StatusInternalServerError)
+				}
+				return
 			}
-			return
-		}
+			if !active {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				err := json.NewEncoder(w).Encode(utils.MakeErr("account is not active"))
+				if err != nil {
+					http.Error(w, "failed to encode response", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-		var ctx context.Context
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			ctx = context.WithValue(r.Context(), "username", claims["username"])
-		}
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+func parseToken(keys *jwtkeys.KeySet, tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
 }
 
-func parseToken(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return secretKey, nil
-	})
+// RequireRole only lets through employees holding at least one of roles, it must run after New
+func RequireRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, _ := r.Context().Value("roles").([]string)
+			for _, role := range granted {
+				if slices.Contains(roles, role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			err := json.NewEncoder(w).Encode(utils.MakeErr("forbidden"))
+			if err != nil {
+				http.Error(w, "failed to encode response", http.StatusInternalServerError)
+			}
+		})
+	}
 }
@@ -0,0 +1,137 @@
+package mwidempotency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/wdsjk/avito-shop/internal/idempotency"
+	"github.com/wdsjk/avito-shop/internal/lib/utils"
+)
+
+// New replays the stored response when a request is retried with the same Idempotency-Key,
+// requests without the header are passed through untouched. It must run after Auth
+func New(service *idempotency.IdempotencyService, log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/idempotency"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotency.HeaderKey)
+			username, _ := r.Context().Value("username").(string)
+			if key == "" || username == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeErr(w, log, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// the same key sent to another endpoint or with other query parameters is a different request
+			request := append([]byte(r.Method+" "+r.URL.RequestURI()+"\n"), body...)
+			rec, err := service.Begin(r.Context(), username, key, request)
+			if err != nil {
+				switch {
+				case errors.Is(err, idempotency.ErrInvalidKey):
+					writeErr(w, log, http.StatusBadRequest, "invalid idempotency key")
+				case errors.Is(err, idempotency.ErrKeyReused):
+					writeErr(w, log, http.StatusUnprocessableEntity, "idempotency key was used with a different request")
+				case errors.Is(err, idempotency.ErrInProgress):
+					writeErr(w, log, http.StatusConflict, "request with this idempotency key is in progress")
+				default:
+					log.Error("failed to reserve idempotency key", "error", err)
+					writeErr(w, log, http.StatusInternalServerError, "internal server error")
+				}
+				return
+			}
+
+			if rec != nil {
+				if rec.ContentType != "" {
+					w.Header().Set("Content-Type", rec.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(rec.StatusCode)
+				if _, err := w.Write(rec.Body); err != nil {
+					log.Error("failed to write replayed response", "error", err)
+				}
+				return
+			}
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+
+			// the outcome has to be recorded even if the client has already gone away
+			ctx := context.WithoutCancel(r.Context())
+			// the key is only released if the request had no effect, a panic included
+			release := true
+			defer func() {
+				if !release {
+					return
+				}
+				if err := service.Release(ctx, username, key); err != nil {
+					log.Error("failed to release idempotency key", "error", err)
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			// server errors and rate limits are not final, a retry should run the request again
+			if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+				return
+			}
+
+			// anything else may have taken effect already, if the response can't be stored the key
+			// stays reserved until it expires, so a retry is refused rather than run a second time
+			release = false
+			if err := complete(ctx, service, username, key, status, ww.Header().Get("Content-Type"), buf.Bytes()); err != nil {
+				log.Error("failed to store idempotent response, the key stays reserved", "error", err)
+			}
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// completeAttempts is how many times storing a response is tried before giving up on it
+const completeAttempts = 3
+
+func complete(ctx context.Context, service *idempotency.IdempotencyService, username, key string, status int, contentType string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= completeAttempts; attempt++ {
+		if err = service.Complete(ctx, username, key, status, contentType, body); err == nil {
+			return nil
+		}
+		if attempt < completeAttempts {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+	}
+
+	return err
+}
+
+func writeErr(w http.ResponseWriter, log *slog.Logger, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(utils.MakeErr(msg))
+	if err != nil {
+		log.Error("failed to encode response", "error", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,147 @@
+package mwidempotency
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wdsjk/avito-shop/internal/idempotency"
+)
+
+// memoryRepo keeps idempotency records in a map, records never expire
+type memoryRepo struct {
+	mu           sync.Mutex
+	records      map[string]*idempotency.Record
+	failComplete bool
+}
+
+func (m *memoryRepo) Reserve(_ context.Context, name, key, requestHash string, expiresAt time.Time) (*idempotency.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rec, ok := m.records[name+"/"+key]; ok {
+		copied := *rec
+		return &copied, nil
+	}
+	m.records[name+"/"+key] = &idempotency.Record{EmployeeName: name, Key: key, RequestHash: requestHash, ExpiresAt: expiresAt}
+	return nil, nil
+}
+
+func (m *memoryRepo) Complete(_ context.Context, name, key string, statusCode int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failComplete {
+		return errors.New("database is down")
+	}
+	rec := m.records[name+"/"+key]
+	rec.StatusCode, rec.ContentType, rec.Body = statusCode, contentType, body
+	return nil
+}
+
+func (m *memoryRepo) Release(_ context.Context, name, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, name+"/"+key)
+	return nil
+}
+
+func (m *memoryRepo) DeleteExpired(context.Context) (int64, error) {
+	return 0, nil
+}
+
+func TestQueryIsPartOfTheRequest(t *testing.T) {
+	service := idempotency.NewIdempotencyService(&memoryRepo{records: make(map[string]*idempotency.Record)}, time.Hour)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	calls := 0
+	handler := New(service, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, r.URL.Query().Get("dryRun"))
+	}))
+
+	send := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader("name,amount\nalice,10\n"))
+		req.Header.Set(idempotency.HeaderKey, "adjust-1")
+		req = req.WithContext(context.WithValue(req.Context(), "username", "admin"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		status   int
+		replayed bool
+		calls    int
+	}{
+		{"dry run", "/api/admin/coins/adjustments?dryRun=true&reason=bonus", http.StatusOK, false, 1},
+		{"dry run retried", "/api/admin/coins/adjustments?dryRun=true&reason=bonus", http.StatusOK, true, 1},
+		{"real run with the dry run's key", "/api/admin/coins/adjustments?dryRun=false&reason=bonus", http.StatusUnprocessableEntity, false, 1},
+		{"other reason with the dry run's key", "/api/admin/coins/adjustments?dryRun=true&reason=fix", http.StatusUnprocessableEntity, false, 1},
+	}
+	for _, tt := range tests {
+		rec := send(tt.target)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if replayed := rec.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.replayed {
+			t.Errorf("%s: replayed %v, want %v", tt.name, replayed, tt.replayed)
+		}
+		if calls != tt.calls {
+			t.Errorf("%s: handler ran %d times, want %d", tt.name, calls, tt.calls)
+		}
+	}
+}
+
+func TestKeyStaysReservedWhenResponseIsNotStored(t *testing.T) {
+	repo := &memoryRepo{records: make(map[string]*idempotency.Record), failComplete: true}
+	service := idempotency.NewIdempotencyService(repo, time.Hour)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name     string
+		status   int
+		reserved bool
+	}{
+		{"success", http.StatusOK, true},
+		{"client error", http.StatusBadRequest, true},
+		{"server error", http.StatusInternalServerError, false},
+		{"rate limited", http.StatusTooManyRequests, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := New(service, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+			}))
+
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodPost, "/api/sendCoin", strings.NewReader(`{"toUser": "bob", "amount": 10}`))
+				req.Header.Set(idempotency.HeaderKey, tt.name)
+				req = req.WithContext(context.WithValue(req.Context(), "username", "alice"))
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			// a reserved key refuses the retry, a released one lets it run again
+			want := 2
+			if tt.reserved {
+				want = 1
+			}
+			if calls != want {
+				t.Errorf("handler ran %d times, want %d", calls, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package ledger
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// system accounts, every other account belongs to an employee
+const (
+	AccountShop     = "shop"     // receives coins spent on purchases
+	AccountIssuance = "issuance" // source of every coin given to employees
+	AccountExpired  = "expired"  // receives coins that were held for too long
+)
+
+const (
+	KindOpening  = "opening"  // balances that existed before the ledger
+	KindIssuance = "issuance" // welcome grants, shown in the coin history
+	KindTransfer = "transfer"
+	KindPurchase = "purchase"
+	KindRefund   = "refund"
+	// coins granted or clawed back by an admin
+	KindGrant      = "grant"
+	KindClawback   = "clawback"
+	KindAdjustment = "adjustment" // a batch with both grants and clawbacks
+	KindAllowance  = "allowance"
+	KindExpiry     = "expiry"
+)
+
+const (
+	MaxBatchSize    = 1000
+	MaxReasonLength = 500
+)
+
+var (
+	ErrEmptyBatch         = errors.New("batch has no adjustments")
+	ErrBatchTooLarge      = errors.New("batch has too many adjustments")
+	ErrReasonRequired     = errors.New("reason is required")
+	ErrReasonTooLong      = errors.New("reason is too long")
+	ErrInvalidAdjustment  = errors.New("adjustment amount must not be zero")
+	ErrDuplicateEmployee  = errors.New("employee appears in the batch more than once")
+	ErrReservedAdjustment = errors.New("account can't be adjusted")
+)
+
+func EmployeeAccount(name string) string {
+	return "employee:" + name
+}
+
+// EmployeeName returns the employee an account belongs to, false for the other accounts
+func EmployeeName(account string) (string, bool) {
+	name, ok := strings.CutPrefix(account, EmployeeAccount(""))
+	return name, ok && name != ""
+}
+
+type Transaction struct {
+	ID         int64     `db:"id"`
+	Kind       string    `db:"kind"`
+	OrderID    *int      `db:"order_id"`
+	TransferID *int      `db:"transfer_id"`
+	Reason     string    `db:"reason"`
+	Actor      string    `db:"actor"` // employee who made a manual transaction
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+type Entry struct {
+	ID            int64  `db:"id"`
+	TransactionID int64  `db:"transaction_id"`
+	Account       string `db:"account"`
+	Amount        int    `db:"amount"` // positive credits the account, negative debits it
+}
+
+// Movement moves Amount coins from one account to another, it becomes a pair of entries
+type Movement struct {
+	From   string
+	To     string
+	Amount int
+}
+
+// Adjustment changes one employee's balance, a positive amount grants coins, a negative one claws them back
+type Adjustment struct {
+	Employee string
+	Amount   int
+}
+
+// Batch is a set of adjustments applied all at once or not at all
+type Batch struct {
+	Adjustments []*Adjustment
+	Reason      string
+	Actor       string
+	DryRun      bool // report what would change without changing anything
+}
+
+func (b *Batch) Kind() string {
+	var grants, clawbacks bool
+	for _, a := range b.Adjustments {
+		grants = grants || a.Amount > 0
+		clawbacks = clawbacks || a.Amount < 0
+	}
+
+	switch {
+	case grants && clawbacks:
+		return KindAdjustment
+	case clawbacks:
+		return KindClawback
+	default:
+		return KindGrant
+	}
+}
+
+// AdjustmentError tells which employee of a batch the batch failed on
+type AdjustmentError struct {
+	Employee string
+	Err      error
+}
+
+func (e *AdjustmentError) Error() string {
+	return e.Employee + ": " + e.Err.Error()
+}
+
+func (e *AdjustmentError) Unwrap() error {
+	return e.Err
+}
+
+type AdjustmentResult struct {
+	Employee string
+	Amount   int
+	Before   int
+	After    int
+}
+
+type BatchResult struct {
+	TransactionID int64 // zero for a dry run
+	Kind          string
+	Adjustments   []*AdjustmentResult
+	Total         int // net amount issued, negative if more was clawed back
+	DryRun        bool
+}
+
+// Mismatch is an employee whose cached balance disagrees with the ledger
+type Mismatch struct {
+	Employee string
+	Cached   int
+	Ledger   int
+}
+
+type Reconciliation struct {
+	Total      int     // sum of every entry, zero in a consistent ledger
+	Unbalanced []int64 // transactions whose entries don't sum to zero
+	Mismatches []*Mismatch
+	CheckedAt  time.Time
+}
+
+func (r *Reconciliation) OK() bool {
+	return r.Total == 0 && len(r.Unbalanced) == 0 && len(r.Mismatches) == 0
+}
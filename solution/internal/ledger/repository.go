@@ -0,0 +1,8 @@
+package ledger
+
+import "context"
+
+type Repository interface {
+	Reconcile(ctx context.Context) (*Reconciliation, error)
+	Adjust(ctx context.Context, batch *Batch) (*BatchResult, error)
+}
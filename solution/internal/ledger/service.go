@@ -0,0 +1,54 @@
+package ledger
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+type LedgerService struct {
+	repo Repository
+}
+
+func NewLedgerService(repo Repository) *LedgerService {
+	return &LedgerService{repo: repo}
+}
+
+// Reconcile checks that the ledger sums to zero and agrees with cached employee balances
+func (s *LedgerService) Reconcile(ctx context.Context) (*Reconciliation, error) {
+	return s.repo.Reconcile(ctx)
+}
+
+// Adjust grants or claws back coins, every adjustment of the batch is applied in a single ledger transaction
+func (s *LedgerService) Adjust(ctx context.Context, batch *Batch) (*BatchResult, error) {
+	batch.Reason = strings.TrimSpace(batch.Reason)
+	if batch.Reason == "" {
+		return nil, ErrReasonRequired
+	}
+	if utf8.RuneCountInString(batch.Reason) > MaxReasonLength {
+		return nil, ErrReasonTooLong
+	}
+
+	switch {
+	case len(batch.Adjustments) == 0:
+		return nil, ErrEmptyBatch
+	case len(batch.Adjustments) > MaxBatchSize:
+		return nil, ErrBatchTooLarge
+	}
+
+	seen := make(map[string]struct{}, len(batch.Adjustments))
+	for _, a := range batch.Adjustments {
+		if a.Amount == 0 {
+			return nil, ErrInvalidAdjustment
+		}
+		if strings.TrimSpace(a.Employee) == "" {
+			return nil, ErrReservedAdjustment
+		}
+		if _, ok := seen[a.Employee]; ok {
+			return nil, ErrDuplicateEmployee
+		}
+		seen[a.Employee] = struct{}{}
+	}
+
+	return s.repo.Adjust(ctx, batch)
+}
@@ -0,0 +1,245 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey  = errors.New("unknown signing key")
+	ErrRetiredKey  = errors.New("signing key is retired")
+	ErrNoActiveKey = errors.New("active signing key is not configured")
+)
+
+// Config describes one key of the set, the private key is only needed by keys that may become active
+type Config struct {
+	ID             string    `yaml:"id"`
+	Algorithm      string    `yaml:"algorithm"` // RS256 or EdDSA
+	PrivateKeyFile string    `yaml:"private_key_file"`
+	PublicKeyFile  string    `yaml:"public_key_file"`
+	RetireAt       time.Time `yaml:"retire_at"` // tokens signed with the key are rejected afterwards, zero never retires
+}
+
+type Key struct {
+	ID        string
+	Algorithm string
+	RetireAt  time.Time
+	private   crypto.Signer
+	public    crypto.PublicKey
+}
+
+func (k *Key) retired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && !now.Before(k.RetireAt)
+}
+
+// KeySet signs tokens with its active key and verifies tokens signed with any key that is not retired yet,
+// so the active key can be rotated without logging everyone out
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeySet(active string, configs []Config) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key, len(configs))}
+	for _, cfg := range configs {
+		key, err := loadKey(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", cfg.ID, err)
+		}
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("key %q: duplicate id", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	key, ok := set.keys[active]
+	if !ok || key.private == nil {
+		return nil, ErrNoActiveKey
+	}
+	set.active = key
+
+	return set, nil
+}
+
+// Ephemeral creates a set with a single Ed25519 key that lives as long as the process,
+// tokens don't survive a restart, so it is only suitable for local development
+func Ephemeral() (*KeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: "ephemeral", Algorithm: AlgEdDSA, private: private, public: public}
+	return &KeySet{active: key, keys: map[string]*Key{key.ID: key}}, nil
+}
+
+// Sign signs claims with the active key and names it in the kid header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(s.active.Algorithm), claims)
+	token.Header["kid"] = s.active.ID
+
+	return token.SignedString(s.active.private)
+}
+
+// Keyfunc picks the verification key by the kid header for jwt.Parse
+func (s *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if key.retired(time.Now()) {
+		return nil, ErrRetiredKey
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+// Algorithms lists the algorithms of the set for jwt.WithValidMethods
+func (s *KeySet) Algorithms() []string {
+	seen := make(map[string]struct{}, 2)
+	algs := make([]string, 0, 2)
+	for _, key := range s.keys {
+		if _, ok := seen[key.Algorithm]; !ok {
+			seen[key.Algorithm] = struct{}{}
+			algs = append(algs, key.Algorithm)
+		}
+	}
+
+	return algs
+}
+
+// JWK is the public part of a key as described by RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public keys other services should accept, retired keys are left out
+func (s *KeySet) JWKS() []JWK {
+	now := time.Now()
+	jwks := make([]JWK, 0, len(s.keys))
+	for _, key := range s.keys {
+		if key.retired(now) {
+			continue
+		}
+
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks = append(jwks, jwk)
+	}
+	sort.Slice(jwks, func(i, j int) bool {
+		return jwks[i].Kid < jwks[j].Kid
+	})
+
+	return jwks
+}
+
+func loadKey(cfg Config) (*Key, error) {
+	if cfg.ID == "" {
+		return nil, errors.New("id is required")
+	}
+
+	key := &Key{ID: cfg.ID, Algorithm: cfg.Algorithm, RetireAt: cfg.RetireAt}
+	switch {
+	case cfg.PrivateKeyFile != "":
+		block, err := readPEM(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			// RSA keys generated by older openssl versions come in PKCS#1
+			rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if rsaErr != nil {
+				return nil, err
+			}
+			private = rsaKey
+		}
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		key.private = signer
+		key.public = signer.Public()
+	case cfg.PublicKeyFile != "":
+		block, err := readPEM(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key.public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("either private_key_file or public_key_file is required")
+	}
+
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		if key.Algorithm != AlgRS256 {
+			return nil, fmt.Errorf("RSA key can't be used with %q", key.Algorithm)
+		}
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+	case ed25519.PublicKey:
+		if key.Algorithm != AlgEdDSA {
+			return nil, fmt.Errorf("Ed25519 key can't be used with %q", key.Algorithm)
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,37 @@
+package mapper
+
+import (
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/ledger"
+)
+
+func AdjustCoinsResponse(res *ledger.BatchResult) *handlers_dto.AdjustCoinsResponse {
+	resp := &handlers_dto.AdjustCoinsResponse{
+		TransactionID: res.TransactionID,
+		Kind:          res.Kind,
+		DryRun:        res.DryRun,
+		Total:         res.Total,
+		Adjustments: make([]struct {
+			Employee string `json:"employee"`
+			Amount   int    `json:"amount"`
+			Before   int    `json:"before"`
+			After    int    `json:"after"`
+		}, 0, len(res.Adjustments)),
+	}
+
+	for _, a := range res.Adjustments {
+		resp.Adjustments = append(resp.Adjustments, struct {
+			Employee string `json:"employee"`
+			Amount   int    `json:"amount"`
+			Before   int    `json:"before"`
+			After    int    `json:"after"`
+		}{
+			Employee: a.Employee,
+			Amount:   a.Amount,
+			Before:   a.Before,
+			After:    a.After,
+		})
+	}
+
+	return resp
+}
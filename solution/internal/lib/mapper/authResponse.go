@@ -2,10 +2,14 @@ package mapper
 
 import (
 	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/session"
 )
 
-func AuthResponse(token string) *handlers_dto.AuthResponse {
+func AuthResponse(token string, sess *session.Session) *handlers_dto.AuthResponse {
 	return &handlers_dto.AuthResponse{
-		Token: token,
+		Token:            token,
+		ExpiresAt:        sess.AccessExpiresAt,
+		RefreshToken:     sess.RefreshToken,
+		RefreshExpiresAt: sess.RefreshExpiresAt,
 	}
 }
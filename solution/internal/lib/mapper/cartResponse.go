@@ -0,0 +1,34 @@
+package mapper
+
+import (
+	"github.com/wdsjk/avito-shop/internal/cart"
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+)
+
+func CartResponse(c *cart.Cart) *handlers_dto.CartResponse {
+	resp := &handlers_dto.CartResponse{
+		Items: make([]struct {
+			Item      string `json:"item"`
+			Quantity  int    `json:"quantity"`
+			UnitPrice int    `json:"unitPrice"`
+			Available bool   `json:"available"`
+		}, 0, len(c.Lines)),
+		Total: c.Total,
+	}
+
+	for _, line := range c.Lines {
+		resp.Items = append(resp.Items, struct {
+			Item      string `json:"item"`
+			Quantity  int    `json:"quantity"`
+			UnitPrice int    `json:"unitPrice"`
+			Available bool   `json:"available"`
+		}{
+			Item:      line.Name,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+			Available: line.Available,
+		})
+	}
+
+	return resp
+}
@@ -0,0 +1,30 @@
+package mapper
+
+import (
+	"github.com/wdsjk/avito-shop/internal/employee"
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+)
+
+func EmployeeResponse(emp *employee.Employee) *handlers_dto.EmployeeResponse {
+	return &handlers_dto.EmployeeResponse{
+		Name:       emp.Name,
+		Status:     emp.Status,
+		Department: emp.Department,
+		CreatedAt:  emp.CreatedAt,
+	}
+}
+
+func InviteResponse(invite *employee.Invite) *handlers_dto.InviteResponse {
+	return &handlers_dto.InviteResponse{
+		Code:       invite.Code,
+		Department: invite.Department,
+		ExpiresAt:  invite.ExpiresAt,
+	}
+}
+
+func RolesResponse(name string, roles []string) *handlers_dto.RolesResponse {
+	return &handlers_dto.RolesResponse{
+		Name:  name,
+		Roles: roles,
+	}
+}
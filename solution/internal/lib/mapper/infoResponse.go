@@ -1,12 +1,15 @@
 package mapper
 
 import (
+	"time"
+
 	"github.com/wdsjk/avito-shop/internal/employee"
 	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/lot"
 	"github.com/wdsjk/avito-shop/internal/transfer"
 )
 
-func InfoResponse(emp *employee.Employee, coinHistory []*transfer.TransferDto) *handlers_dto.InfoResponse {
+func InfoResponse(emp *employee.Employee, coinHistory []*transfer.Summary, expirations []*lot.Expiration) *handlers_dto.InfoResponse {
 	resp := &handlers_dto.InfoResponse{
 		Coins: emp.Coins,
 		Inventory: make([]struct {
@@ -17,21 +20,33 @@ func InfoResponse(emp *employee.Employee, coinHistory []*transfer.TransferDto) *
 			Received []struct {
 				FromUser string `json:"fromUser"`
 				Amount   int    `json:"amount"`
+				Message  string `json:"message,omitempty"`
+				Tag      string `json:"tag,omitempty"`
 			} `json:"received"`
 			Sent []struct {
-				ToUser string `json:"toUser"`
-				Amount int    `json:"amount"`
+				ToUser  string `json:"toUser"`
+				Amount  int    `json:"amount"`
+				Message string `json:"message,omitempty"`
+				Tag     string `json:"tag,omitempty"`
 			} `json:"sent"`
 		}{
 			Received: make([]struct {
 				FromUser string `json:"fromUser"`
 				Amount   int    `json:"amount"`
+				Message  string `json:"message,omitempty"`
+				Tag      string `json:"tag,omitempty"`
 			}, 0),
 			Sent: make([]struct {
-				ToUser string `json:"toUser"`
-				Amount int    `json:"amount"`
+				ToUser  string `json:"toUser"`
+				Amount  int    `json:"amount"`
+				Message string `json:"message,omitempty"`
+				Tag     string `json:"tag,omitempty"`
 			}, 0),
 		},
+		ExpiringCoins: make([]struct {
+			Amount    int       `json:"amount"`
+			ExpiresAt time.Time `json:"expiresAt"`
+		}, 0, len(expirations)),
 	}
 
 	for item, count := range emp.Inventory {
@@ -44,26 +59,44 @@ func InfoResponse(emp *employee.Employee, coinHistory []*transfer.TransferDto) *
 		})
 	}
 
-	for _, t := range coinHistory {
-		switch emp.Name {
-		case t.ReceiverName:
+	for _, s := range coinHistory {
+		switch s.Direction {
+		case transfer.DirectionReceived:
 			resp.CoinHistory.Received = append(resp.CoinHistory.Received, struct {
 				FromUser string `json:"fromUser"`
 				Amount   int    `json:"amount"`
+				Message  string `json:"message,omitempty"`
+				Tag      string `json:"tag,omitempty"`
 			}{
-				FromUser: t.SenderName,
-				Amount:   t.Amount,
+				FromUser: s.Counterpart,
+				Amount:   s.Amount,
+				Message:  s.Message,
+				Tag:      s.Tag,
 			})
-		case t.SenderName:
+		case transfer.DirectionSent:
 			resp.CoinHistory.Sent = append(resp.CoinHistory.Sent, struct {
-				ToUser string `json:"toUser"`
-				Amount int    `json:"amount"`
+				ToUser  string `json:"toUser"`
+				Amount  int    `json:"amount"`
+				Message string `json:"message,omitempty"`
+				Tag     string `json:"tag,omitempty"`
 			}{
-				ToUser: t.ReceiverName,
-				Amount: t.Amount,
+				ToUser:  s.Counterpart,
+				Amount:  s.Amount,
+				Message: s.Message,
+				Tag:     s.Tag,
 			})
 		}
 	}
 
+	for _, e := range expirations {
+		resp.ExpiringCoins = append(resp.ExpiringCoins, struct {
+			Amount    int       `json:"amount"`
+			ExpiresAt time.Time `json:"expiresAt"`
+		}{
+			Amount:    e.Amount,
+			ExpiresAt: e.ExpiresAt,
+		})
+	}
+
 	return resp
 }
@@ -0,0 +1,18 @@
+package mapper
+
+import (
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+func ItemResponse(item *shop.Item) *handlers_dto.ItemResponse {
+	return &handlers_dto.ItemResponse{
+		Name:        item.Name,
+		Price:       item.Price,
+		Description: item.Description,
+		Active:      item.Active,
+		Stock:       item.Stock,
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+	}
+}
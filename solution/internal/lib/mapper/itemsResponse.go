@@ -0,0 +1,39 @@
+package mapper
+
+import (
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/shop"
+)
+
+func ItemsResponse(items []*shop.Item, total int, filter *shop.ListFilter) *handlers_dto.ItemsResponse {
+	resp := &handlers_dto.ItemsResponse{
+		Items: make([]struct {
+			Name        string `json:"name"`
+			Price       int    `json:"price"`
+			Description string `json:"description"`
+			Available   bool   `json:"available"`
+			Stock       *int   `json:"stock"`
+		}, 0, len(items)),
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	for _, item := range items {
+		resp.Items = append(resp.Items, struct {
+			Name        string `json:"name"`
+			Price       int    `json:"price"`
+			Description string `json:"description"`
+			Available   bool   `json:"available"`
+			Stock       *int   `json:"stock"`
+		}{
+			Name:        item.Name,
+			Price:       item.Price,
+			Description: item.Description,
+			Available:   item.Available(),
+			Stock:       item.Stock,
+		})
+	}
+
+	return resp
+}
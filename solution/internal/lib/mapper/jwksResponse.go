@@ -0,0 +1,12 @@
+package mapper
+
+import (
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
+)
+
+func JWKSResponse(keys []jwtkeys.JWK) *handlers_dto.JWKSResponse {
+	return &handlers_dto.JWKSResponse{
+		Keys: keys,
+	}
+}
@@ -0,0 +1,50 @@
+package mapper
+
+import (
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/order"
+)
+
+func OrderResponse(o *order.Order) *handlers_dto.OrderResponse {
+	resp := &handlers_dto.OrderResponse{
+		ID: o.ID,
+		Items: make([]struct {
+			Item      string `json:"item"`
+			Quantity  int    `json:"quantity"`
+			UnitPrice int    `json:"unitPrice"`
+		}, 0, len(o.Items)),
+		Total:       o.Total,
+		Status:      o.Status,
+		CreatedAt:   o.CreatedAt,
+		CancelledAt: o.CancelledAt,
+	}
+
+	for _, line := range o.Items {
+		resp.Items = append(resp.Items, struct {
+			Item      string `json:"item"`
+			Quantity  int    `json:"quantity"`
+			UnitPrice int    `json:"unitPrice"`
+		}{
+			Item:      line.Item,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+		})
+	}
+
+	return resp
+}
+
+func OrdersResponse(orders []*order.Order, total, limit, offset int) *handlers_dto.OrdersResponse {
+	resp := &handlers_dto.OrdersResponse{
+		Orders: make([]*handlers_dto.OrderResponse, 0, len(orders)),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	for _, o := range orders {
+		resp.Orders = append(resp.Orders, OrderResponse(o))
+	}
+
+	return resp
+}
@@ -0,0 +1,35 @@
+package mapper
+
+import (
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/ledger"
+)
+
+func ReconciliationResponse(rec *ledger.Reconciliation) *handlers_dto.ReconciliationResponse {
+	resp := &handlers_dto.ReconciliationResponse{
+		OK:         rec.OK(),
+		Total:      rec.Total,
+		Unbalanced: make([]int64, 0, len(rec.Unbalanced)),
+		Mismatches: make([]struct {
+			Employee string `json:"employee"`
+			Cached   int    `json:"cached"`
+			Ledger   int    `json:"ledger"`
+		}, 0, len(rec.Mismatches)),
+		CheckedAt: rec.CheckedAt,
+	}
+
+	resp.Unbalanced = append(resp.Unbalanced, rec.Unbalanced...)
+	for _, m := range rec.Mismatches {
+		resp.Mismatches = append(resp.Mismatches, struct {
+			Employee string `json:"employee"`
+			Cached   int    `json:"cached"`
+			Ledger   int    `json:"ledger"`
+		}{
+			Employee: m.Employee,
+			Cached:   m.Cached,
+			Ledger:   m.Ledger,
+		})
+	}
+
+	return resp
+}
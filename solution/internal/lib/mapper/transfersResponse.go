@@ -0,0 +1,45 @@
+package mapper
+
+import (
+	"time"
+
+	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/transfer"
+)
+
+func TransfersResponse(page *transfer.Page) *handlers_dto.TransfersResponse {
+	resp := &handlers_dto.TransfersResponse{
+		Transfers: make([]struct {
+			ID        int       `json:"id"`
+			FromUser  string    `json:"fromUser"`
+			ToUser    string    `json:"toUser"`
+			Amount    int       `json:"amount"`
+			Message   string    `json:"message,omitempty"`
+			Tag       string    `json:"tag,omitempty"`
+			CreatedAt time.Time `json:"createdAt"`
+		}, 0, len(page.Transfers)),
+		NextCursor: page.NextCursor,
+	}
+
+	for _, t := range page.Transfers {
+		resp.Transfers = append(resp.Transfers, struct {
+			ID        int       `json:"id"`
+			FromUser  string    `json:"fromUser"`
+			ToUser    string    `json:"toUser"`
+			Amount    int       `json:"amount"`
+			Message   string    `json:"message,omitempty"`
+			Tag       string    `json:"tag,omitempty"`
+			CreatedAt time.Time `json:"createdAt"`
+		}{
+			ID:        t.ID,
+			FromUser:  t.SenderName,
+			ToUser:    t.ReceiverName,
+			Amount:    t.Amount,
+			Message:   t.Message,
+			Tag:       t.Tag,
+			CreatedAt: t.CreatedAt,
+		})
+	}
+
+	return resp
+}
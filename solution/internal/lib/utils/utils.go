@@ -5,17 +5,21 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	handlers_dto "github.com/wdsjk/avito-shop/internal/infra/transport/http/handlers/dto"
+	"github.com/wdsjk/avito-shop/internal/lib/jwtkeys"
 )
 
 func MakeErr(msg string) handlers_dto.ErrorResponse {
 	return handlers_dto.ErrorResponse{Errors: msg}
 }
 
-func GenerateJWT(username string, secret []byte) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// GenerateJWT signs an access token, jti identifies it so it can be revoked before it expires.
+// Roles are only as fresh as the token, a change reaches the employee with the next refresh
+func GenerateJWT(keys *jwtkeys.KeySet, username string, roles []string, jti string, expiresAt time.Time) (string, error) {
+	return keys.Sign(jwt.MapClaims{
 		"username": username,
-		"exp":      time.Now().Add(24 * time.Hour).Unix(),
+		"roles":    roles,
+		"jti":      jti,
+		"iat":      time.Now().Unix(),
+		"exp":      expiresAt.Unix(),
 	})
-
-	return token.SignedString(secret)
 }
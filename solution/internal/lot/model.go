@@ -0,0 +1,24 @@
+package lot
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidLifetime = errors.New("coin lifetime must be positive")
+
+// Lot is coins an employee was granted at one time, they expire together
+type Lot struct {
+	ID            int64     `db:"id"`
+	Employee      string    `db:"employee"`
+	GrantedAt     time.Time `db:"granted_at"` // kept when the coins move to another employee
+	Amount        int       `db:"amount"`
+	Remaining     int       `db:"remaining"`
+	TransactionID *int64    `db:"transaction_id"` // nil for balances from before lots
+}
+
+// Expiration is coins of an employee that expire at about the same time
+type Expiration struct {
+	Amount    int
+	ExpiresAt time.Time
+}
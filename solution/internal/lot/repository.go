@@ -0,0 +1,13 @@
+package lot
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Expire takes the coins granted before cutoff off every balance and returns how many employees lost coins
+	Expire(ctx context.Context, cutoff time.Time) (int64, error)
+	// Upcoming sums the employee's coins granted before until per day, GrantedAt is the earliest grant of the day
+	Upcoming(ctx context.Context, name string, until time.Time) ([]*Lot, error)
+}
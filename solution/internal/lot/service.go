@@ -0,0 +1,47 @@
+package lot
+
+import (
+	"context"
+	"time"
+)
+
+type LotService struct {
+	repo     Repository
+	lifetime int // months
+	notice   time.Duration
+}
+
+// NewLotService lets coins expire lifetime months after they were granted, employees
+// see them in their upcoming expirations notice ahead
+func NewLotService(repo Repository, lifetime int, notice time.Duration) (*LotService, error) {
+	if lifetime <= 0 {
+		return nil, ErrInvalidLifetime
+	}
+
+	return &LotService{repo: repo, lifetime: lifetime, notice: notice}, nil
+}
+
+func (s *LotService) ExpiresAt(grantedAt time.Time) time.Time {
+	return grantedAt.AddDate(0, s.lifetime, 0)
+}
+
+// Expire takes the coins that are past their lifetime off the balances
+func (s *LotService) Expire(ctx context.Context) (int64, error) {
+	return s.repo.Expire(ctx, time.Now().AddDate(0, -s.lifetime, 0))
+}
+
+// Upcoming lists the employee's coins that expire within the notice period, oldest first.
+// Coins already past their lifetime are listed until the expiry job takes them
+func (s *LotService) Upcoming(ctx context.Context, name string) ([]*Expiration, error) {
+	lots, err := s.repo.Upcoming(ctx, name, time.Now().Add(s.notice).AddDate(0, -s.lifetime, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	expirations := make([]*Expiration, 0, len(lots))
+	for _, l := range lots {
+		expirations = append(expirations, &Expiration{Amount: l.Remaining, ExpiresAt: s.ExpiresAt(l.GrantedAt)})
+	}
+
+	return expirations, nil
+}
@@ -0,0 +1,48 @@
+package order
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+var (
+	ErrInvalidPage         = errors.New("invalid page")
+	ErrCancelWindowExpired = errors.New("order can no longer be cancelled")
+)
+
+type Order struct {
+	ID           int        `db:"id"`
+	EmployeeName string     `db:"employee_name"`
+	Items        []*Line    `db:"-"`
+	Total        int        `db:"total"`
+	Status       string     `db:"status"`
+	CreatedAt    time.Time  `db:"created_at"`
+	CancelledAt  *time.Time `db:"cancelled_at"`
+	CancelledBy  *string    `db:"cancelled_by"`
+}
+
+// Cancellation describes who cancels an order and under which restrictions
+type Cancellation struct {
+	Owner        string    // only this employee's order may be cancelled, empty allows any
+	Actor        string    // employee performing the cancellation
+	CreatedAfter time.Time // orders placed earlier can't be cancelled, zero allows any
+}
+
+// Line is one item of an order with the price it was bought at
+type Line struct {
+	Item      string `db:"item"`
+	Quantity  int    `db:"quantity"`
+	UnitPrice int    `db:"unit_price"`
+}
+
+func (l *Line) Subtotal() int {
+	return l.Quantity * l.UnitPrice
+}
@@ -0,0 +1,9 @@
+package order
+
+import "context"
+
+type Repository interface {
+	GetOrder(ctx context.Context, name string, id int) (*Order, error)
+	ListOrders(ctx context.Context, name string, limit, offset int) ([]*Order, int, error)
+	CancelOrder(ctx context.Context, id int, cancel Cancellation) (*Order, error)
+}
@@ -0,0 +1,47 @@
+package order
+
+import (
+	"context"
+	"time"
+)
+
+type OrderService struct {
+	repo         Repository
+	cancelWindow time.Duration
+}
+
+func NewOrderService(repo Repository, cancelWindow time.Duration) *OrderService {
+	return &OrderService{repo: repo, cancelWindow: cancelWindow}
+}
+
+// GetOrder returns one of the employee's orders, orders of other employees are not found
+func (s *OrderService) GetOrder(ctx context.Context, name string, id int) (*Order, error) {
+	return s.repo.GetOrder(ctx, name, id)
+}
+
+// ListOrders returns a page of the employee's orders, newest first, and their total count
+func (s *OrderService) ListOrders(ctx context.Context, name string, limit, offset int) ([]*Order, int, error) {
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit < 0 || limit > MaxListLimit || offset < 0 {
+		return nil, 0, ErrInvalidPage
+	}
+
+	return s.repo.ListOrders(ctx, name, limit, offset)
+}
+
+// CancelOrder lets an employee undo their own order within the cancel window,
+// cancelling an already cancelled order just returns it
+func (s *OrderService) CancelOrder(ctx context.Context, name string, id int) (*Order, error) {
+	return s.repo.CancelOrder(ctx, id, Cancellation{
+		Owner:        name,
+		Actor:        name,
+		CreatedAfter: time.Now().Add(-s.cancelWindow),
+	})
+}
+
+// RefundOrder is the admin override of CancelOrder, it works on any order at any time
+func (s *OrderService) RefundOrder(ctx context.Context, admin string, id int) (*Order, error) {
+	return s.repo.CancelOrder(ctx, id, Cancellation{Actor: admin})
+}
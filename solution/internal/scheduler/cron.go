@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// descriptors are the shorthands standard cron accepts for common schedules
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field is a set of allowed values, bit i allows value i
+type field uint64
+
+func (f field) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var (
+	minutes = bounds{"minute", 0, 59}
+	hours   = bounds{"hour", 0, 23}
+	days    = bounds{"day of month", 1, 31}
+	months  = bounds{"month", 1, 12}
+	weekday = bounds{"day of week", 0, 7} // both 0 and 7 are Sunday
+)
+
+// Schedule is a parsed cron expression: minute, hour, day of month, month and day of week
+type Schedule struct {
+	expr    string
+	minute  field
+	hour    field
+	day     field
+	month   field
+	weekday field
+	// a restricted day of month and day of week match if either does, like in cron
+	anyDay, anyWeekday bool
+	loc                *time.Location
+}
+
+// ParseSchedule parses a five field cron expression or one of the @hourly, @daily,
+// @weekly, @monthly and @yearly shorthands, the schedule runs in loc
+func ParseSchedule(expr string, loc *time.Location) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q: expected 5 fields", ErrInvalidSchedule, expr)
+	}
+
+	s := &Schedule{expr: expr, loc: loc}
+	targets := []*field{&s.minute, &s.hour, &s.day, &s.month, &s.weekday}
+	for i, b := range []bounds{minutes, hours, days, months, weekday} {
+		f, err := parseField(fields[i], b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %s", ErrInvalidSchedule, expr, err)
+		}
+		*targets[i] = f
+	}
+	if s.weekday.has(7) {
+		s.weekday |= 1
+	}
+	s.anyDay = fields[2] == "*"
+	s.anyWeekday = fields[4] == "*"
+
+	return s, nil
+}
+
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after t the schedule fires at, or the zero time if it never does
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+
+	// a schedule like "0 0 30 2 *" never fires, give up instead of looping forever
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		y, m, d := t.Date()
+		switch {
+		case !s.month.has(int(m)):
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, s.loc)
+		case !s.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, s.loc)
+		case !s.hour.has(t.Hour()):
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, s.loc)
+		case !s.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day, weekday := s.day.has(t.Day()), s.weekday.has(int(t.Weekday()))
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+
+	return day || weekday
+}
+
+// parseField parses a comma separated list of *, values, ranges and /steps over them
+func parseField(spec string, b bounds) (field, error) {
+	var f field
+	for _, part := range strings.Split(spec, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s %q", b.name, part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := b.min, b.max
+		if rng != "*" {
+			var err error
+			from, to, isRange := strings.Cut(rng, "-")
+			if lo, err = parseValue(from, b); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseValue(to, b); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the end in steps of 15
+				hi = b.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %s %q", b.name, part)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			f |= 1 << uint(v)
+		}
+	}
+
+	return f, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > b.max {
+		return 0, fmt.Errorf("invalid %s %q", b.name, s)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Disabled in place of a schedule turns the job off
+const Disabled = "off"
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+
+	// MaxAttempts is how many times a failed run is tried before the job moves on to its next slot
+	MaxAttempts = 3
+	// RetryDelay is how long after a failure the run is tried again
+	RetryDelay = 5 * time.Minute
+	// Lease is how long a running run is left to the replica running it before the others
+	// check the lock, a replica that died without finishing is noticed after it
+	Lease = 10 * time.Minute
+)
+
+var (
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	ErrDuplicateJob    = errors.New("job is already registered")
+)
+
+// JobFunc does the work of a job for the slot at scheduledAt, it may run more than once for
+// the same slot after a failure, so it has to be idempotent
+type JobFunc func(ctx context.Context, scheduledAt time.Time) error
+
+type Job struct {
+	Name     string
+	Schedule *Schedule
+	Run      JobFunc
+}
+
+// Run is one execution of a job for one slot of its schedule
+type Run struct {
+	ID          int64      `db:"id"`
+	Job         string     `db:"job"`
+	ScheduledAt time.Time  `db:"scheduled_at"`
+	Status      string     `db:"status"`
+	Attempts    int        `db:"attempts"`
+	Error       string     `db:"error"`
+	StartedAt   time.Time  `db:"started_at"`
+	FinishedAt  *time.Time `db:"finished_at"`
+}
@@ -0,0 +1,14 @@
+package scheduler
+
+import "context"
+
+type Repository interface {
+	// Lock takes the job's lock without waiting, only the replica holding it runs the job.
+	// unlock has to be called once the run is finished
+	Lock(ctx context.Context, job string) (unlock func(), ok bool, err error)
+	// LastRun returns the run with the latest slot, nil if the job never ran
+	LastRun(ctx context.Context, job string) (*Run, error)
+	// StartRun records the run as running, a retry of a slot counts another attempt
+	StartRun(ctx context.Context, run *Run) error
+	FinishRun(ctx context.Context, run *Run) error
+}
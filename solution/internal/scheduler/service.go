@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SchedulerService runs jobs on their cron schedules. Every replica runs the scheduler, a
+// Postgres advisory lock makes sure a slot is run by one of them only, and the runs table
+// lets a restarted replica pick up a slot that was missed while nothing was running
+type SchedulerService struct {
+	repo    Repository
+	tick    time.Duration
+	log     *slog.Logger
+	jobs    []*Job
+	started time.Time
+}
+
+// NewSchedulerService checks for due jobs every tick, a minute matches the cron resolution
+func NewSchedulerService(repo Repository, tick time.Duration, log *slog.Logger) *SchedulerService {
+	return &SchedulerService{repo: repo, tick: tick, log: log}
+}
+
+func (s *SchedulerService) Register(name string, schedule *Schedule, run JobFunc) error {
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return fmt.Errorf("%w: %s", ErrDuplicateJob, name)
+		}
+	}
+
+	s.jobs = append(s.jobs, &Job{Name: name, Schedule: schedule, Run: run})
+	return nil
+}
+
+// Start runs due jobs until ctx is done
+func (s *SchedulerService) Start(ctx context.Context) {
+	s.started = time.Now()
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	for {
+		for _, job := range s.jobs {
+			if err := s.runDue(ctx, job, time.Now()); err != nil {
+				s.log.Error("failed to run job", "job", job.Name, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SchedulerService) runDue(ctx context.Context, job *Job, now time.Time) error {
+	// checked without the lock first, so idle ticks don't take a connection per job
+	last, err := s.repo.LastRun(ctx, job.Name)
+	if err != nil {
+		return err
+	}
+	if _, _, ok := s.due(job, last, now, false); !ok {
+		return nil
+	}
+
+	unlock, ok, err := s.repo.Lock(ctx, job.Name)
+	if err != nil || !ok {
+		return err // another replica is running the job
+	}
+	defer unlock()
+
+	// the slot may have been run by another replica in the meantime
+	last, err = s.repo.LastRun(ctx, job.Name)
+	if err != nil {
+		return err
+	}
+	slot, attempt, ok := s.due(job, last, now, true)
+	if !ok {
+		return nil
+	}
+
+	run := &Run{Job: job.Name, ScheduledAt: slot, Attempts: attempt, StartedAt: time.Now()}
+	if err := s.repo.StartRun(ctx, run); err != nil {
+		return err
+	}
+	s.log.Debug("job started", "job", job.Name, "scheduled_at", slot, "attempt", attempt)
+
+	runErr := s.execute(ctx, job, slot)
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = StatusSucceeded
+	if runErr != nil {
+		run.Status = StatusFailed
+		run.Error = runErr.Error()
+		s.log.Error("job failed", "job", job.Name, "scheduled_at", slot, "attempt", attempt, "error", runErr)
+	} else {
+		s.log.Info("job finished", "job", job.Name, "scheduled_at", slot, "duration", finishedAt.Sub(run.StartedAt))
+	}
+
+	// the outcome is recorded even if the scheduler is stopping
+	return s.repo.FinishRun(context.WithoutCancel(ctx), run)
+}
+
+// due returns the slot to run now and which attempt at it that is. A failed run is retried
+// first, missed slots are collapsed into the latest one. A running run is only stale if
+// the caller holds the lock, without it the run is left alone until its lease is over
+func (s *SchedulerService) due(job *Job, last *Run, now time.Time, locked bool) (time.Time, int, bool) {
+	if last != nil && last.Attempts < MaxAttempts {
+		// slots come back from the database in UTC, jobs see them in the schedule's location
+		slot := last.ScheduledAt.In(job.Schedule.loc)
+		switch {
+		case last.Status == StatusRunning && locked:
+			return slot, last.Attempts + 1, true
+		case last.Status == StatusRunning && now.Before(last.StartedAt.Add(Lease)):
+			return time.Time{}, 0, false
+		case last.Status == StatusRunning:
+			return slot, 0, true
+		case last.Status == StatusFailed && last.FinishedAt != nil && !now.Before(last.FinishedAt.Add(RetryDelay)):
+			return slot, last.Attempts + 1, true
+		case last.Status == StatusFailed:
+			return time.Time{}, 0, false
+		}
+	}
+
+	// a job that never ran starts with the first slot after the scheduler started
+	from := s.started
+	if last != nil {
+		from = last.ScheduledAt
+	}
+
+	slot := job.Schedule.Next(from)
+	if slot.IsZero() || slot.After(now) {
+		return time.Time{}, 0, false
+	}
+	for next := job.Schedule.Next(slot); !next.IsZero() && !next.After(now); next = job.Schedule.Next(next) {
+		slot = next
+	}
+
+	return slot, 1, true
+}
+
+// execute runs the job, a panic fails the run instead of taking the process down
+func (s *SchedulerService) execute(ctx context.Context, job *Job, slot time.Time) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return job.Run(ctx, slot)
+}
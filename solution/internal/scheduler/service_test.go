@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDueLeavesRunningRunsAlone(t *testing.T) {
+	schedule, err := ParseSchedule("@hourly", time.UTC)
+	if err != nil {
+		t.Fatalf("parse schedule: %v", err)
+	}
+	job := &Job{Name: "test", Schedule: schedule}
+	s := &SchedulerService{}
+
+	slot := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	running := &Run{Job: job.Name, ScheduledAt: slot, Status: StatusRunning, Attempts: 1, StartedAt: slot}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		locked  bool
+		due     bool
+		attempt int
+	}{
+		{"within the lease", slot.Add(Lease / 2), false, false, 0},
+		{"lease over", slot.Add(Lease), false, true, 0},
+		{"stale under the lock", slot.Add(Lease / 2), true, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, attempt, due := s.due(job, running, tt.now, tt.locked)
+			if due != tt.due || attempt != tt.attempt {
+				t.Fatalf("due %v attempt %d, want due %v attempt %d", due, attempt, tt.due, tt.attempt)
+			}
+			if due && !got.Equal(slot) {
+				t.Errorf("slot %v, want %v", got, slot)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// Session is what a client gets after logging in or refreshing: the claims of a short-lived
+// access token and the opaque refresh token to get the next one
+type Session struct {
+	EmployeeName     string
+	AccessJTI        string
+	AccessExpiresAt  time.Time
+	RefreshToken     string // only known right after it is issued, the database keeps its hash
+	RefreshExpiresAt time.Time
+}
+
+type RefreshToken struct {
+	ID              int        `db:"id"`
+	FamilyID        string     `db:"family_id"` // shared by all tokens rotated from the same login
+	EmployeeName    string     `db:"employee_name"`
+	TokenHash       string     `db:"token_hash"`
+	AccessJTI       string     `db:"access_jti"`
+	AccessExpiresAt time.Time  `db:"access_expires_at"`
+	CreatedAt       time.Time  `db:"created_at"`
+	ExpiresAt       time.Time  `db:"expires_at"`
+	UsedAt          *time.Time `db:"used_at"`
+	RevokedAt       *time.Time `db:"revoked_at"`
+}
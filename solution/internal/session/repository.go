@@ -0,0 +1,18 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	SaveRefreshToken(ctx context.Context, token *RefreshToken) error
+	// RotateRefreshToken marks the token with hash as used and saves next in its family. Presenting
+	// an already used token revokes the whole family and fails with ErrRefreshTokenReused
+	RotateRefreshToken(ctx context.Context, hash string, next *RefreshToken) (*RefreshToken, error)
+	// RevokeFamily revokes the refresh tokens of hash's family along with the access tokens issued with them
+	RevokeFamily(ctx context.Context, hash string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,128 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+type SessionService struct {
+	repo       Repository
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewSessionService(repo Repository, accessTTL, refreshTTL time.Duration) *SessionService {
+	return &SessionService{
+		repo:       repo,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Start opens a new session, i.e. a new refresh token family, for the employee
+func (s *SessionService) Start(ctx context.Context, name string) (*Session, error) {
+	family, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, token, err := s.next(name, family)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveRefreshToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return sess, nil
+}
+
+// Refresh exchanges a refresh token for a new session, the old token can't be used again
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string) (*Session, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// the family and the owner are filled in from the rotated token
+	sess, token, err := s.next("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	prev, err := s.repo.RotateRefreshToken(ctx, hashToken(refreshToken), token)
+	if err != nil {
+		return nil, err
+	}
+	sess.EmployeeName = prev.EmployeeName
+
+	return sess, nil
+}
+
+// Logout revokes the access token with jti and, if given, the refresh token family it came with
+func (s *SessionService) Logout(ctx context.Context, refreshToken, jti string, accessExpiresAt time.Time) error {
+	if refreshToken != "" {
+		if err := s.repo.RevokeFamily(ctx, hashToken(refreshToken)); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.RevokeAccessToken(ctx, jti, accessExpiresAt)
+}
+
+func (s *SessionService) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.repo.IsRevoked(ctx, jti)
+}
+
+// Purge removes expired refresh tokens and denylist entries of expired access tokens
+func (s *SessionService) Purge(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx)
+}
+
+func (s *SessionService) next(name, family string) (*Session, *RefreshToken, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, nil, err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	sess := &Session{
+		EmployeeName:     name,
+		AccessJTI:        jti,
+		AccessExpiresAt:  now.Add(s.accessTTL),
+		RefreshToken:     refresh,
+		RefreshExpiresAt: now.Add(s.refreshTTL),
+	}
+	token := &RefreshToken{
+		FamilyID:        family,
+		EmployeeName:    name,
+		TokenHash:       hashToken(refresh),
+		AccessJTI:       sess.AccessJTI,
+		AccessExpiresAt: sess.AccessExpiresAt,
+		CreatedAt:       now,
+		ExpiresAt:       sess.RefreshExpiresAt,
+	}
+
+	return sess, token, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,19 +1,51 @@
 package shop
 
-// not db model, because shop items are static and infinite
-type Shop map[string]int
-
-func NewShop() Shop {
-	return Shop{
-		"t-shirt":    80,
-		"cup":        20,
-		"book":       50,
-		"pen":        10,
-		"powerbank":  200,
-		"hoody":      300,
-		"umbrella":   200,
-		"socks":      10,
-		"wallet":     50,
-		"pink-hoody": 500,
-	}
+import (
+	"errors"
+	"time"
+)
+
+const (
+	SortByName      = "name"
+	SortByPriceAsc  = "price"
+	SortByPriceDesc = "-price"
+
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+
+	// MaxQuantity caps how many units of one item a single purchase may take
+	MaxQuantity = 1000
+)
+
+var (
+	ErrItemInactive  = errors.New("item is not available")
+	ErrInvalidFilter = errors.New("invalid list filter")
+	ErrBadQuantity   = errors.New("invalid quantity")
+	// returned when restocking an item without a stock limit
+	ErrUnlimitedStock = errors.New("item stock is unlimited")
+)
+
+type Item struct {
+	ID          int       `db:"id"`
+	Name        string    `db:"name"`
+	Price       int       `db:"price"`
+	Description string    `db:"description"`
+	Active      bool      `db:"active"`
+	Stock       *int      `db:"stock"` // nil if unlimited
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// Available reports whether the item can be bought right now
+func (i *Item) Available() bool {
+	return i.Active && (i.Stock == nil || *i.Stock > 0)
+}
+
+type ListFilter struct {
+	MinPrice  *int
+	MaxPrice  *int
+	Available *bool // nil lists both available and unavailable items
+	Sort      string
+	Limit     int
+	Offset    int
 }
@@ -0,0 +1,14 @@
+package shop
+
+import "context"
+
+type Repository interface {
+	SaveItem(ctx context.Context, item *Item) (*Item, error)
+	// UpdateItem leaves the stock alone unless setStock, a nil Stock then makes the item unlimited
+	UpdateItem(ctx context.Context, item *Item, setStock bool) (*Item, error)
+	DeleteItem(ctx context.Context, name string) error
+	GetItem(ctx context.Context, name string) (*Item, error)
+	GetItems(ctx context.Context, names []string) ([]*Item, error)
+	ListItems(ctx context.Context, filter ListFilter) ([]*Item, int, error)
+	Restock(ctx context.Context, name string, quantity int) (*Item, error)
+}
@@ -1,9 +1,98 @@
 package shop
 
+import "context"
+
 type ShopService struct {
-	shop Shop
+	repo Repository
+}
+
+func NewShopService(repo Repository) *ShopService {
+	return &ShopService{repo: repo}
+}
+
+func (s *ShopService) CreateItem(ctx context.Context, item *Item) (*Item, error) {
+	return s.repo.SaveItem(ctx, item)
+}
+
+// UpdateItem changes the item, its stock only if setStock. Setting the stock is how an
+// unlimited item gets a limit, which restocking doesn't give it
+func (s *ShopService) UpdateItem(ctx context.Context, item *Item, setStock bool) (*Item, error) {
+	if setStock && item.Stock != nil && *item.Stock < 0 {
+		return nil, ErrBadQuantity
+	}
+
+	return s.repo.UpdateItem(ctx, item, setStock)
+}
+
+func (s *ShopService) DeleteItem(ctx context.Context, name string) error {
+	return s.repo.DeleteItem(ctx, name)
+}
+
+func (s *ShopService) GetItem(ctx context.Context, name string) (*Item, error) {
+	return s.repo.GetItem(ctx, name)
+}
+
+func (s *ShopService) Restock(ctx context.Context, name string, quantity int) (*Item, error) {
+	if quantity <= 0 {
+		return nil, ErrBadQuantity
+	}
+
+	return s.repo.Restock(ctx, name, quantity)
+}
+
+// GetItems looks up several items at once, names missing from the catalog are absent from the result
+func (s *ShopService) GetItems(ctx context.Context, names []string) (map[string]*Item, error) {
+	items := make(map[string]*Item, len(names))
+	if len(names) == 0 {
+		return items, nil
+	}
+
+	found, err := s.repo.GetItems(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range found {
+		items[item.Name] = item
+	}
+
+	return items, nil
 }
 
-func NewShopService(shop Shop) *ShopService {
-	return &ShopService{shop: shop}
+// GetActiveItem returns an item only if it can currently be bought
+func (s *ShopService) GetActiveItem(ctx context.Context, name string) (*Item, error) {
+	item, err := s.repo.GetItem(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !item.Active {
+		return nil, ErrItemInactive
+	}
+
+	return item, nil
+}
+
+// ListItems returns a page of the catalog and the total number of matching items,
+// filling in defaults for the omitted filter fields
+func (s *ShopService) ListItems(ctx context.Context, filter *ListFilter) ([]*Item, int, error) {
+	switch filter.Sort {
+	case "":
+		filter.Sort = SortByName
+	case SortByName, SortByPriceAsc, SortByPriceDesc:
+	default:
+		return nil, 0, ErrInvalidFilter
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit < 0 || filter.Limit > MaxListLimit || filter.Offset < 0 {
+		return nil, 0, ErrInvalidFilter
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return nil, 0, ErrInvalidFilter
+	}
+
+	return s.repo.ListItems(ctx, *filter)
 }
@@ -1,22 +1,108 @@
 package transfer
 
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DirectionAll      = ""
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+
+	// CounterpartWelcomeGrant is who welcome grants are received from in the coin history
+	CounterpartWelcomeGrant = "welcome grant"
+
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+	// HistoryLimit caps the ungrouped coin history, older transfers are paged through ListTransfers
+	HistoryLimit = MaxListLimit
+)
+
+var (
+	ErrInvalidFilter = errors.New("invalid transfer filter")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
 type Transfer struct {
-	ID           int    `db:"id"`
-	SenderName   string `db:"sender_name"`
-	ReceiverName string `db:"receiver_name"` // "" if transfer to shop
-	Amount       int    `db:"amount"`
+	ID           int       `db:"id"`
+	SenderName   string    `db:"sender_name"`
+	ReceiverName string    `db:"receiver_name"` // "" if transfer to shop
+	Amount       int       `db:"amount"`
+	Message      string    `db:"message"`
+	Tag          string    `db:"tag"`
+	CreatedAt    time.Time `db:"created_at"`
 }
 
 type TransferDto struct {
-	SenderName   string `json:"sender_name"`
-	ReceiverName string `json:"receiver_name"`
-	Amount       int    `json:"amount"`
+	ID           int       `json:"id"`
+	SenderName   string    `json:"sender_name"`
+	ReceiverName string    `json:"receiver_name"`
+	Amount       int       `json:"amount"`
+	Message      string    `json:"message"`
+	Tag          string    `json:"tag"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 func ToDto(t *Transfer) *TransferDto {
 	return &TransferDto{
+		ID:           t.ID,
 		SenderName:   t.SenderName,
 		ReceiverName: t.ReceiverName,
 		Amount:       t.Amount,
+		Message:      t.Message,
+		Tag:          t.Tag,
+		CreatedAt:    t.CreatedAt,
 	}
 }
+
+// Cursor points at the last transfer of a page, transfers are listed newest first
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+func (c *Cursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", c.CreatedAt.UnixNano(), c.ID))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var nanos int64
+	var id int
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &nanos, &id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: time.Unix(0, nanos), ID: id}, nil
+}
+
+// Summary is the coin history with one counterpart in one direction,
+// ungrouped history has a summary per transfer
+type Summary struct {
+	Counterpart string `db:"counterpart"`
+	Direction   string `db:"direction"` // DirectionSent or DirectionReceived
+	Amount      int    `db:"amount"`
+	Count       int    `db:"count"`
+	Message     string `db:"message"` // only set in ungrouped history
+	Tag         string `db:"tag"`     // only set in ungrouped history
+}
+
+type ListFilter struct {
+	Direction string     // DirectionAll, DirectionSent or DirectionReceived
+	From      *time.Time // inclusive
+	To        *time.Time // exclusive
+	After     *Cursor    // nil for the first page
+	Limit     int
+}
+
+type Page struct {
+	Transfers  []*TransferDto
+	NextCursor string // empty on the last page
+}
@@ -0,0 +1,60 @@
+package transfer
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxMessageLength = 280
+	MaxTagLength     = 32
+)
+
+var (
+	ErrMessageTooLong = errors.New("message is too long")
+	ErrInvalidTag     = errors.New("invalid tag")
+)
+
+// Note is the optional thank-you message and category attached to a transfer
+type Note struct {
+	Message string
+	Tag     string
+}
+
+// NewNote sanitizes a message and a tag coming from a client
+func NewNote(message, tag string) (Note, error) {
+	message = sanitizeMessage(message)
+	if utf8.RuneCountInString(message) > MaxMessageLength {
+		return Note{}, ErrMessageTooLong
+	}
+
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return Note{}, ErrInvalidTag
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '-' && r != '_' {
+			return Note{}, ErrInvalidTag
+		}
+	}
+
+	return Note{Message: message, Tag: tag}, nil
+}
+
+// sanitizeMessage drops control and invisible formatting characters (zero width
+// spaces, bidi overrides) and collapses whitespace, so a message renders as one line
+func sanitizeMessage(message string) string {
+	message = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r), r == utf8.RuneError:
+			return -1
+		}
+		return r
+	}, message)
+
+	return strings.Join(strings.Fields(message), " ")
+}
@@ -4,5 +4,7 @@ import "context"
 
 type Repository interface {
 	SaveTransfer(ctx context.Context, senderName, receiverName string, amount int) error
-	GetTransfersByEmployee(ctx context.Context, name string) ([]*Transfer, error)
+	GetTransfersByEmployee(ctx context.Context, name string, limit int) ([]*Transfer, error)
+	GetTransferSummary(ctx context.Context, name string) ([]*Summary, error)
+	ListTransfers(ctx context.Context, name string, filter ListFilter) ([]*Transfer, error)
 }
@@ -0,0 +1,98 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// names of the transfer rules, as reported in violations
+const (
+	RuleMaxPerTransfer          = "max_per_transfer"
+	RuleMaxPerDay               = "max_per_day"
+	RuleMaxPerCounterpartPerDay = "max_per_counterpart_per_day"
+	RuleCooldown                = "cooldown"
+	RuleReturnCooldown          = "return_cooldown"
+)
+
+var ErrRuleViolation = errors.New("transfer is not allowed")
+
+// Rules limit what an employee may send, a zero limit or cooldown doesn't apply.
+// Days start at midnight in Location
+type Rules struct {
+	MaxPerTransfer          int           // coins in a single transfer
+	MaxPerDay               int           // coins sent in a day
+	MaxPerCounterpartPerDay int           // transfers to the same colleague in a day
+	Cooldown                time.Duration // between two transfers of the sender
+	ReturnCooldown          time.Duration // before coins go back to a colleague who just sent some, against ping-pong
+	Location                *time.Location
+}
+
+// Stats is the sender's recent activity the rules are checked against, it has to be taken
+// with the sender locked, or concurrent transfers could slip past the daily limits together
+type Stats struct {
+	SentToday           int       // coins
+	ToCounterpartToday  int       // transfers
+	LastSent            time.Time // zero if the sender never sent anything
+	LastFromCounterpart time.Time // when the receiver last sent coins to the sender
+}
+
+// RuleViolation is a transfer one of the rules doesn't allow. RetryAfter tells when
+// the same transfer would be allowed again, zero if waiting doesn't help
+type RuleViolation struct {
+	Rule       string
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (v *RuleViolation) Error() string {
+	return fmt.Sprintf("%s: %s", ErrRuleViolation, v.Rule)
+}
+
+func (v *RuleViolation) Is(target error) bool {
+	return target == ErrRuleViolation
+}
+
+// StartOfDay returns the midnight the day containing t started at
+func (r Rules) StartOfDay(t time.Time) time.Time {
+	loc := r.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// Check returns a *RuleViolation if sending amount at now breaks one of the rules
+func (r Rules) Check(amount int, stats *Stats, now time.Time) error {
+	if r.MaxPerTransfer > 0 && amount > r.MaxPerTransfer {
+		return &RuleViolation{Rule: RuleMaxPerTransfer, Limit: r.MaxPerTransfer}
+	}
+
+	if r.Cooldown > 0 && !stats.LastSent.IsZero() {
+		if wait := stats.LastSent.Add(r.Cooldown).Sub(now); wait > 0 {
+			return &RuleViolation{Rule: RuleCooldown, RetryAfter: wait}
+		}
+	}
+	if r.ReturnCooldown > 0 && !stats.LastFromCounterpart.IsZero() {
+		if wait := stats.LastFromCounterpart.Add(r.ReturnCooldown).Sub(now); wait > 0 {
+			return &RuleViolation{Rule: RuleReturnCooldown, RetryAfter: wait}
+		}
+	}
+
+	tomorrow := r.StartOfDay(now).AddDate(0, 0, 1).Sub(now)
+	if r.MaxPerDay > 0 && stats.SentToday+amount > r.MaxPerDay {
+		v := &RuleViolation{Rule: RuleMaxPerDay, Limit: r.MaxPerDay}
+		// an amount above the limit won't fit tomorrow either
+		if amount <= r.MaxPerDay {
+			v.RetryAfter = tomorrow
+		}
+		return v
+	}
+	if r.MaxPerCounterpartPerDay > 0 && stats.ToCounterpartToday >= r.MaxPerCounterpartPerDay {
+		return &RuleViolation{Rule: RuleMaxPerCounterpartPerDay, Limit: r.MaxPerCounterpartPerDay, RetryAfter: tomorrow}
+	}
+
+	return nil
+}
@@ -14,16 +14,67 @@ func (s *TransferService) SaveTransfer(ctx context.Context, senderName, receiver
 	return s.repo.SaveTransfer(ctx, senderName, receiverName, amount)
 }
 
-func (s *TransferService) GetTransfersByEmployee(ctx context.Context, name string) ([]*TransferDto, error) {
-	transfers, err := s.repo.GetTransfersByEmployee(ctx, name)
+// GetCoinHistory sums the employee's transfers per counterpart and direction, unless
+// grouped is false, then the latest HistoryLimit transfers are reported on their own
+func (s *TransferService) GetCoinHistory(ctx context.Context, name string, grouped bool) ([]*Summary, error) {
+	if grouped {
+		return s.repo.GetTransferSummary(ctx, name)
+	}
+
+	transfers, err := s.repo.GetTransfersByEmployee(ctx, name, HistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*Summary, 0, len(transfers))
+	for _, t := range transfers {
+		summary := &Summary{Amount: t.Amount, Count: 1, Message: t.Message, Tag: t.Tag}
+		if t.SenderName == name {
+			summary.Counterpart, summary.Direction = t.ReceiverName, DirectionSent
+		} else {
+			summary.Counterpart, summary.Direction = t.SenderName, DirectionReceived
+		}
+		history = append(history, summary)
+	}
+
+	return history, nil
+}
+
+// ListTransfers returns a page of transfers between the employee and colleagues, newest first
+func (s *TransferService) ListTransfers(ctx context.Context, name string, filter ListFilter) (*Page, error) {
+	switch filter.Direction {
+	case DirectionAll, DirectionSent, DirectionReceived:
+	default:
+		return nil, ErrInvalidFilter
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit < 0 || filter.Limit > MaxListLimit {
+		return nil, ErrInvalidFilter
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, ErrInvalidFilter
+	}
+
+	// one extra row tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	transfers, err := s.repo.ListTransfers(ctx, name, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	var dtos []*TransferDto
+	page := &Page{Transfers: make([]*TransferDto, 0, limit)}
+	if len(transfers) > limit {
+		transfers = transfers[:limit]
+		last := transfers[limit-1]
+		page.NextCursor = (&Cursor{CreatedAt: last.CreatedAt, ID: last.ID}).Encode()
+	}
 	for _, t := range transfers {
-		dtos = append(dtos, ToDto(t))
+		page.Transfers = append(page.Transfers, ToDto(t))
 	}
 
-	return dtos, nil
+	return page, nil
 }